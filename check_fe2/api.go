@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+type InputService struct {
+	Name  string `json:"name"`
+	ID    string `json:"id"`
+	State string `json:"state"`
+}
+type InputServiceDetail struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	State   string `json:"state"`
+}
+
+type Amweb struct {
+	Id               string `json:"identifier"`
+	Name             string `json:"name"`
+	Organisation     string `json:"organization"`
+	ConnectionType   string `json:"connectionType"`
+	ConnectionState  string `json:"connectionState"`
+	ConnectionsCount int    `json:"nbrOfWebSocketConnections"`
+}
+
+type CloudService struct {
+	Name  string `json:"service"`
+	State string `json:"state"`
+}
+
+type RedundancyState struct {
+	State      string `json:"state"`
+	Current    string `json:"current"`
+	Configured string `json:"configured"`
+}
+
+type Status struct {
+	State             string          `json:"state"`
+	Message           string          `json:"message"`
+	NbrOfLoggedErrors int             `json:"nbrOfLoggedErrors"`
+	RedundancyState   RedundancyState `json:"redundancyState"`
+}
+
+type Mqtt struct {
+	Defaultbroker string `json:"defaultBroker"`
+	Kubernetes    string `json:"kubernetes"`
+}
+
+// APIError beschreibt eine Antwort der Monitoring-Schnittstelle mit unerwartetem HTTP-Statuscode
+type APIError struct {
+	Endpoint   string
+	StatusCode int
+	Status     string
+}
+
+func (e *APIError) Error() string {
+	return "unexpected HTTP status: " + e.Status
+}
+
+// apiGet ruft einen Endpunkt der Monitoring-Schnittstelle ab und dekodiert die JSON-Antwort in target.
+// Dauer und Ergebnis jeder Anfrage werden für den Service "FE2 API" protokolliert.
+func apiGet(config Config, endpoint string, target interface{}) error {
+	if config.breaker != nil {
+		if err := config.breaker.allow(); err != nil {
+			return err
+		}
+	}
+	start := time.Now()
+	err := getJSON(config, endpoint, target)
+	duration := time.Since(start)
+	if config.breaker != nil {
+		config.breaker.record(err)
+	}
+	if config.requests != nil {
+		config.requests.add(endpoint, duration, err)
+	}
+	// Fehler werden dort mit Level info geloggt, wo sie behandelt werden (logCheckFailure)
+	entry := config.logger().WithFields(endpointFields(endpoint)).WithField("duration_ms", milliseconds(duration))
+	if err != nil {
+		entry.WithError(err).Debug("FE2 request failed")
+	} else {
+		entry.Debug("FE2 request")
+	}
+	return err
+}
+
+func getJSON(config Config, endpoint string, target interface{}) error {
+	apiURL := config.ApiURL + endpoint
+	// Erstellen Sie eine HTTP-Anfrage mit dem Authorization-Header
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return fmt.Errorf("error creating HTTP request: %w", err)
+	}
+
+	req.Header.Set("Authorization", config.Token)
+	// Führen Sie die Anfrage durch
+	client := newHTTPClient(config)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+	// Überprüfen Sie den HTTP-Statuscode
+	if resp.StatusCode != http.StatusOK {
+		return &APIError{Endpoint: endpoint, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	if config.schema != nil {
+		config.schema.check(endpoint, body, target)
+	}
+	// Dekodieren Sie die JSON-Antwort
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(target); err != nil {
+		return fmt.Errorf("error decoding JSON: %w", err)
+	}
+	return nil
+}
+
+// newHTTPClient erstellt den HTTP-Client für die Anfragen an FE2
+func newHTTPClient(config Config) *http.Client {
+	return &http.Client{Timeout: config.Timeout, Transport: config.Transport}
+}
+
+// getMonitorList führt eine Anfrage durch, um die Eingänge mit ID und Status zu erhalten
+func getMonitorList(config Config) ([]InputService, error) {
+	var services []InputService
+	if err := apiGet(config, "input", &services); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+func getDetailedMonitorInfo(config Config, id string) (InputServiceDetail, error) {
+	var detailedInfo InputServiceDetail
+	if err := apiGet(config, "input/"+id, &detailedInfo); err != nil {
+		return detailedInfo, err
+	}
+	detailedInfo.ID = id
+
+	return detailedInfo, nil
+}
+
+// fetchInputs liefert die Detailinformationen aller Eingänge
+func fetchInputs(config Config) ([]InputServiceDetail, error) {
+	services, err := getMonitorList(config)
+	if err != nil {
+		return nil, err
+	}
+	cache := loadInputCache(config)
+	cache.retain(services)
+	defer cache.save(config)
+	var inputs []InputServiceDetail
+	for _, service := range services {
+		// Gesunde Eingänge können aus der Liste und der zwischengespeicherten Meldung ausgegeben werden
+		if cached, ok := cache.lookup(service); ok {
+			inputs = append(inputs, cached)
+			continue
+		}
+		detailedInfo, err := getDetailedMonitorInfo(config, service.ID)
+		if err != nil {
+			return inputs, err
+		}
+		cache.store(detailedInfo)
+		inputs = append(inputs, detailedInfo)
+	}
+	return inputs, nil
+}
+
+func fetchAmwebs(config Config) ([]Amweb, error) {
+	var amwebs []Amweb
+	err := apiGet(config, "amweb", &amwebs)
+	return amwebs, err
+}
+
+func fetchCloud(config Config) ([]CloudService, error) {
+	var services []CloudService
+	err := apiGet(config, "cloud", &services)
+	return services, err
+}
+
+func fetchStatus(config Config) (Status, error) {
+	var status Status
+	err := apiGet(config, "status", &status)
+	return status, err
+}
+
+func fetchMqtt(config Config) (Mqtt, error) {
+	var mqtt Mqtt
+	err := apiGet(config, "mqtt", &mqtt)
+	return mqtt, err
+}
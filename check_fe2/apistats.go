@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APILevels sind die Schwellwerte des Services "FE2 API"
+type APILevels struct {
+	// ResponseTimeWarn und ResponseTimeCrit gelten für jede einzelne Anfrage, Standard 2s/5s
+	ResponseTimeWarn time.Duration `yaml:"response_time_warn"`
+	ResponseTimeCrit time.Duration `yaml:"response_time_crit"`
+	// FailedWarn und FailedCrit gelten für die Anzahl fehlgeschlagener Endpunkte, Standard 1/3
+	FailedWarn int `yaml:"failed_warn"`
+	FailedCrit int `yaml:"failed_crit"`
+}
+
+func (l APILevels) withDefaults() APILevels {
+	if l.ResponseTimeWarn == 0 {
+		l.ResponseTimeWarn = 2 * time.Second
+	}
+	if l.ResponseTimeCrit == 0 {
+		l.ResponseTimeCrit = 5 * time.Second
+	}
+	if l.FailedWarn == 0 {
+		l.FailedWarn = 1
+	}
+	if l.FailedCrit == 0 {
+		l.FailedCrit = 3
+	}
+	return l
+}
+
+// requestLog protokolliert Dauer und Ergebnis aller Anfragen eines Laufs
+type requestLog struct {
+	mu       sync.Mutex
+	requests []requestTiming
+}
+
+type requestTiming struct {
+	Endpoint   string  `json:"endpoint"`
+	DurationMs float64 `json:"durationMs"`
+	Error      string  `json:"error,omitempty"`
+
+	duration time.Duration
+}
+
+func (l *requestLog) add(endpoint string, duration time.Duration, err error) {
+	timing := requestTiming{Endpoint: endpoint, DurationMs: milliseconds(duration), duration: duration}
+	if err != nil {
+		timing.Error = err.Error()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests = append(l.requests, timing)
+}
+
+func (l *requestLog) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.requests)
+}
+
+// responseTimes liefert je Endpunkt die längste Antwortzeit, die Details der Eingänge als "input_details"
+func (l *requestLog) responseTimes() ([]string, map[string]time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var names []string
+	times := make(map[string]time.Duration)
+	for _, request := range l.requests {
+		name := request.Endpoint
+		if strings.HasPrefix(name, "input/") {
+			name = "input_details"
+		}
+		if _, ok := times[name]; !ok {
+			names = append(names, name)
+		}
+		if request.duration > times[name] {
+			times[name] = request.duration
+		}
+	}
+	return names, times
+}
+
+// evaluateAPI liefert den Service "FE2 API" mit der Antwortzeit je Endpunkt und der Anzahl fehlgeschlagener Endpunkte
+func evaluateAPI(levels APILevels, endpoints []EndpointResult, requests *requestLog) Result {
+	levels = levels.withDefaults()
+	var failed []string
+	for _, endpoint := range endpoints {
+		if endpoint.Err != nil {
+			failed = append(failed, endpoint.Endpoint)
+		}
+	}
+
+	var metrics []Metric
+	names, times := requests.responseTimes()
+	for _, name := range names {
+		// Übersprungene, von FE2 nicht unterstützte Endpunkte werden nicht berücksichtigt
+		if name != "input_details" && !hasEndpoint(endpoints, name) {
+			continue
+		}
+		metrics = append(metrics, Metric{
+			Name:   "response_time_" + name,
+			Value:  times[name].Round(time.Microsecond).Seconds(),
+			Levels: true,
+			Warn:   levels.ResponseTimeWarn.Seconds(),
+			Crit:   levels.ResponseTimeCrit.Seconds(),
+		})
+	}
+	metrics = append(metrics, Metric{Name: "failed_endpoints", Value: float64(len(failed)), Levels: true, Warn: float64(levels.FailedWarn), Crit: float64(levels.FailedCrit)})
+
+	summary := fmt.Sprintf("%d von %d Endpunkten erreichbar, %d Anfragen", len(endpoints)-len(failed), len(endpoints), requests.count())
+	if len(failed) > 0 {
+		summary += ", fehlgeschlagen: " + strings.Join(failed, ", ")
+	}
+	result := Result{
+		State:   StateDynamic,
+		Name:    "FE2 API",
+		Metrics: metrics,
+		Reason:  fmt.Sprintf("Antwortzeit gegen %s/%s, fehlgeschlagene Endpunkte gegen %d/%d", levels.ResponseTimeWarn, levels.ResponseTimeCrit, levels.FailedWarn, levels.FailedCrit),
+		Summary: summary,
+	}
+	// Unabhängig von den Schwellwerten ist die Schnittstelle nicht nutzbar, wenn kein Endpunkt antwortet
+	switch {
+	case len(endpoints) == 0:
+		result.State, result.Reason = StateUnknown, "kein Endpunkt abgefragt"
+	case len(failed) == len(endpoints):
+		result.State, result.Reason = StateCrit, "kein Endpunkt erreichbar"
+	}
+	return result
+}
+
+func hasEndpoint(endpoints []EndpointResult, name string) bool {
+	for _, endpoint := range endpoints {
+		if endpoint.Endpoint == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEvaluateAPI(t *testing.T) {
+	requests := &requestLog{}
+	requests.add("input", 100*time.Millisecond, nil)
+	requests.add("input/a1", 3*time.Second, nil)
+	requests.add("input/b2", 200*time.Millisecond, nil)
+	requests.add("status", 50*time.Millisecond, errors.New("unexpected HTTP status: 500"))
+	endpoints := []EndpointResult{{Endpoint: "input"}, {Endpoint: "status", Err: errors.New("unexpected HTTP status: 500")}}
+
+	result := evaluateAPI(APILevels{}, endpoints, requests)
+	want := `P "FE2 API" response_time_input=0.1;2;5|response_time_input_details=3;2;5|response_time_status=0.05;2;5|failed_endpoints=1;1;3 1 von 2 Endpunkten erreichbar, 4 Anfragen, fehlgeschlagen: status`
+	if got := result.CheckmkLine(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if state := result.EffectiveState(); state != StateWarn {
+		t.Errorf("state = %d, want %d", state, StateWarn)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const authServiceName = "FE2 API Authentication"
+
+// authError liefert den APIError, wenn FE2 die Anfrage mit 401 oder 403 abgelehnt hat
+func authError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden) {
+		return apiErr, true
+	}
+	return nil, false
+}
+
+// markAuthFailures ergänzt Endpunkte, die an der Authentifizierung gescheitert sind, um einen UNKNOWN Service,
+// damit sie nicht kommentarlos aus der Ausgabe verschwinden
+func markAuthFailures(endpoints []EndpointResult) {
+	for i, endpoint := range endpoints {
+		apiErr, ok := authError(endpoint.Err)
+		if !ok {
+			continue
+		}
+		endpoints[i].Results = append(endpoints[i].Results, Result{
+			State:   StateUnknown,
+			Name:    "FE2 API Endpoint: " + endpoint.Endpoint,
+			Reason:  "HTTP " + apiErr.Status,
+			Summary: fmt.Sprintf("Abfrage nicht möglich, siehe Service %s (%s)", authServiceName, apiErr.Status),
+		})
+	}
+}
+
+// evaluateAuthentication liefert den Service "FE2 API Authentication".
+// Er ist CRIT, sobald FE2 eine Anfrage mit 401 oder 403 ablehnt.
+func evaluateAuthentication(endpoints []EndpointResult) Result {
+	result := Result{Name: authServiceName}
+	failed := make(map[string][]string)
+	succeeded := 0
+	for _, endpoint := range endpoints {
+		if apiErr, ok := authError(endpoint.Err); ok {
+			failed[apiErr.Status] = append(failed[apiErr.Status], endpoint.Endpoint)
+		} else if endpoint.Err == nil {
+			succeeded++
+		}
+	}
+
+	switch {
+	case len(failed) > 0:
+		var details []string
+		for status, names := range failed {
+			details = append(details, fmt.Sprintf("%s bei %s", status, strings.Join(names, ", ")))
+		}
+		sort.Strings(details)
+		result.State = StateCrit
+		result.Reason = strings.Join(details, "; ")
+		result.Summary = fmt.Sprintf("FE2 lehnt das Token ab (%s). Das Token des Monitoring Plugins in %s ist ungültig oder hat keine Berechtigung für die Monitoring-Schnittstelle", result.Reason, getConfigFilePath())
+	case succeeded > 0:
+		result.State = StateOK
+		result.Summary = "Token wird von FE2 akzeptiert"
+	default:
+		result.State = StateUnknown
+		result.Summary = "Authentifizierung konnte nicht geprüft werden, keine Anfrage war erfolgreich"
+	}
+	return result
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const capabilitiesFile = "capabilities.json"
+
+// Capabilities merkt sich, welche Endpunkte der Monitoring-Schnittstelle FE2 anbietet.
+// Ältere FE2 Versionen kennen z.B. mqtt oder amweb noch nicht und antworten mit 404.
+type Capabilities struct {
+	Probed      time.Time `json:"probed"`
+	Supported   []string  `json:"supported"`
+	Unsupported []string  `json:"unsupported"`
+	// Known enthält alle Endpunkte, die jemals erfolgreich abgefragt wurden
+	Known []string `json:"known"`
+
+	expired bool
+	// unconfirmed sind Endpunkte mit 404 in einem Lauf, in dem kein Endpunkt erreichbar war
+	unconfirmed []string
+}
+
+// loadCapabilities liest die zwischengespeicherten Capabilities aus dem cache_dir
+func loadCapabilities(config Config) *Capabilities {
+	capabilities := &Capabilities{}
+	if config.CacheDir != "" {
+		data, err := os.ReadFile(filepath.Join(config.CacheDir, capabilitiesFile))
+		if err == nil {
+			err = json.Unmarshal(data, capabilities)
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			config.logger().WithError(err).Warn("Error reading capabilities cache")
+		}
+	}
+	// Nach Ablauf werden alle Endpunkte erneut geprüft
+	capabilities.expired = time.Since(capabilities.Probed) > config.CapabilitiesTTL
+	return capabilities
+}
+
+// save schreibt die Capabilities in das cache_dir
+func (c *Capabilities) save(config Config) {
+	if config.CacheDir == "" {
+		return
+	}
+	if c.expired {
+		c.Probed = time.Now()
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err == nil {
+		err = writeCacheFile(config, capabilitiesFile, data)
+	}
+	if err != nil {
+		config.logger().WithError(err).Warn("Error writing capabilities cache")
+	}
+}
+
+// skip liefert true für Endpunkte, die FE2 laut Cache nicht unterstützt
+func (c *Capabilities) skip(endpoint string) bool {
+	return !c.expired && containsString(c.Unsupported, endpoint)
+}
+
+// notFound liefert true, wenn FE2 den Endpunkt selbst mit 404 beantwortet (nicht z.B. input/<id>)
+func notFound(endpoint string, err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound && apiErr.Endpoint == endpoint
+}
+
+// update wertet das Ergebnis einer Abfrage aus und liefert true, wenn der Endpunkt nicht unterstützt wird
+func (c *Capabilities) update(endpoint string, err error) bool {
+	if notFound(endpoint, err) {
+		c.Supported = removeString(c.Supported, endpoint)
+		c.Unsupported = appendUnique(c.Unsupported, endpoint)
+		return true
+	}
+	if err == nil {
+		c.Unsupported = removeString(c.Unsupported, endpoint)
+		c.Supported = appendUnique(c.Supported, endpoint)
+		c.Known = appendUnique(c.Known, endpoint)
+	}
+	return false
+}
+
+// markNotFound ergänzt Endpunkte mit einem nicht bestätigten 404 um einen UNKNOWN Service
+func markNotFound(endpoints []EndpointResult) {
+	for i, endpoint := range endpoints {
+		if !notFound(endpoint.Endpoint, endpoint.Err) {
+			continue
+		}
+		endpoints[i].Results = append(endpoints[i].Results, Result{
+			State:   StateUnknown,
+			Name:    "FE2 API Endpoint: " + endpoint.Endpoint,
+			Reason:  "HTTP 404",
+			Summary: "Endpunkt nicht gefunden und kein anderer Endpunkt erreichbar, Hostname, Port und Protokoll prüfen",
+		})
+	}
+}
+
+// result liefert den Service "FE2 API Capabilities".
+// Er ist WARN, wenn ein früher vorhandener Endpunkt nicht mehr angeboten wird.
+func (c *Capabilities) result() Result {
+	result := Result{State: StateOK, Name: "FE2 API Capabilities"}
+	var missing []string
+	for _, endpoint := range c.Unsupported {
+		if containsString(c.Known, endpoint) {
+			missing = append(missing, endpoint)
+		}
+	}
+
+	summary := "Unterstützte Endpunkte: " + joinOrDash(c.Supported)
+	if len(c.Unsupported) > 0 {
+		summary += ", nicht unterstützt: " + strings.Join(c.Unsupported, ", ")
+	}
+	if len(missing) > 0 {
+		result.State = StateWarn
+		result.Reason = "früher vorhandene Endpunkte antworten mit 404"
+		summary += ", nicht mehr verfügbar: " + strings.Join(missing, ", ")
+	}
+	result.Summary = summary
+	if len(c.unconfirmed) > 0 {
+		result.State = StateCrit
+		result.Reason = "alle Endpunkte antworten mit 404"
+		result.Summary = "Kein Endpunkt der Monitoring-Schnittstelle gefunden (404: " + strings.Join(c.unconfirmed, ", ") + "), Hostname, Port und Protokoll prüfen"
+	}
+	return result
+}
+
+// unsupportedEndpoint liefert einen UNKNOWN Service für einen einzeln abgefragten Endpunkt, den FE2 nicht anbietet
+func unsupportedEndpoint(endpoint string) EndpointResult {
+	return EndpointResult{Endpoint: endpoint, Results: []Result{{
+		State:   StateUnknown,
+		Name:    "FE2 API Endpoint: " + endpoint,
+		Reason:  "nicht unterstützt",
+		Summary: "Endpunkt " + endpoint + " wird von dieser FE2 Version nicht unterstützt (HTTP 404)",
+	}}}
+}
+
+// writeCacheFile schreibt eine Datei in das cache_dir und legt das Verzeichnis bei Bedarf an
+func writeCacheFile(config Config, name string, data []byte) error {
+	if err := os.MkdirAll(config.CacheDir, 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(config.CacheDir, name), data, 0600)
+}
+
+func appendUnique(values []string, value string) []string {
+	if containsString(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+func removeString(values []string, value string) []string {
+	var result []string
+	for _, v := range values {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func joinOrDash(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ", ")
+}
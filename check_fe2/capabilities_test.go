@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"checkmk_fe2/fe2sim"
+)
+
+func TestCapabilitiesSkipUnsupportedEndpoints(t *testing.T) {
+	fixture := copyFixture(loadFixture(t, "recorded"))
+	// Ältere FE2 Version ohne mqtt Endpunkt
+	delete(fixture.Endpoints, "mqtt")
+	simulator, config := newTestServer(t, fixture)
+	config.CacheDir = t.TempDir()
+	config.CapabilitiesTTL = time.Hour
+
+	for run := 0; run < 2; run++ {
+		endpoints := runChecks(config, "all")
+		for _, endpoint := range endpoints {
+			if endpoint.Endpoint == "mqtt" {
+				t.Errorf("run %d: unsupported endpoint mqtt was reported", run)
+			}
+			if endpoint.Err != nil {
+				t.Errorf("run %d: %s: unexpected error: %v", run, endpoint.Endpoint, endpoint.Err)
+			}
+		}
+		capabilities := endpoints[len(endpoints)-1].Results[0]
+		want := "Unterstützte Endpunkte: input, amweb, cloud, status, nicht unterstützt: mqtt"
+		if capabilities.State != StateOK || capabilities.Summary != want {
+			t.Errorf("run %d: got %d %q, want 0 %q", run, capabilities.State, capabilities.Summary, want)
+		}
+	}
+	// Der zweite Lauf verwendet den Cache und fragt mqtt nicht erneut ab
+	if got := simulator.UnknownRequests("mqtt"); got != 1 {
+		t.Errorf("mqtt was requested %d times, want 1", got)
+	}
+}
+
+func TestCapabilitiesMissingAfterUpgrade(t *testing.T) {
+	fixture := copyFixture(loadFixture(t, "recorded"))
+	simulator, config := newTestServer(t, fixture)
+	config.CacheDir = t.TempDir()
+	runChecks(config, "all")
+
+	// Nach einem Update fehlt der amweb Endpunkt, TTL 0 erzwingt eine erneute Prüfung
+	delete(fixture.Endpoints, "amweb")
+	simulator.SetFixture(fixture)
+	endpoints := runChecks(config, "all")
+	capabilities := endpoints[len(endpoints)-1].Results[0]
+	if capabilities.State != StateWarn {
+		t.Errorf("state = %d, want %d (%s)", capabilities.State, StateWarn, capabilities.Summary)
+	}
+}
+
+func TestCapabilitiesSingleEndpoint(t *testing.T) {
+	fixture := copyFixture(loadFixture(t, "recorded"))
+	delete(fixture.Endpoints, "mqtt")
+	_, config := newTestServer(t, fixture)
+	config.CacheDir = t.TempDir()
+	config.CapabilitiesTTL = time.Hour
+
+	// Ohne bestätigte Capabilities ist ein 404 bei einem einzelnen Endpunkt nicht von einem falschen Port zu unterscheiden
+	out, _ := runCheckmk(config, "mqtt")
+	if want := `3 "FE2 API Endpoint: mqtt" - Endpunkt nicht gefunden`; !strings.Contains(string(out), want) {
+		t.Errorf("output does not contain %q:\n%s", want, out)
+	}
+
+	// Nach einem Lauf mit allen Endpunkten ist mqtt als nicht unterstützt bekannt
+	runCheckmk(config, "all")
+	out, _ = runCheckmk(config, "mqtt")
+	if want := `3 "FE2 API Endpoint: mqtt" - Endpunkt mqtt wird von dieser FE2 Version nicht unterstützt (HTTP 404)`; !strings.Contains(string(out), want) {
+		t.Errorf("output does not contain %q:\n%s", want, out)
+	}
+}
+
+func TestCapabilitiesEverythingNotFound(t *testing.T) {
+	// Ein Webserver, der jeden Pfad mit 404 beantwortet, z.B. bei falschem Port oder Pfad
+	_, config := newTestServer(t, fe2sim.Fixture{Token: "test-token"})
+	config.CacheDir = t.TempDir()
+	config.CapabilitiesTTL = time.Hour
+
+	for run := 1; run <= 2; run++ {
+		endpoints := runChecks(config, "all")
+		states := make(map[string]int)
+		for _, endpoint := range endpoints {
+			for _, result := range endpoint.Results {
+				states[result.Name] = result.EffectiveState()
+			}
+		}
+		for _, name := range []string{"FE2 API Capabilities", "FE2 API"} {
+			if states[name] != StateCrit {
+				t.Errorf("run %d: %s state = %d, want CRIT", run, name, states[name])
+			}
+		}
+		if states["FE2 API Endpoint: input"] != StateUnknown {
+			t.Errorf("run %d: input state = %d, want UNKNOWN", run, states["FE2 API Endpoint: input"])
+		}
+	}
+	// Der Lauf ohne erreichbaren Endpunkt speichert keine nicht unterstützten Endpunkte
+	if capabilities := loadCapabilities(config); len(capabilities.Unsupported) > 0 {
+		t.Errorf("unsupported endpoints were cached: %v", capabilities.Unsupported)
+	}
+}
+
+func TestCapabilitiesAllWithOneEndpoint(t *testing.T) {
+	_, config := newTestServer(t, loadFixture(t, "recorded"))
+	config.Endpoints = map[string]bool{"input": false, "amweb": false, "cloud": false, "status": false}
+	// Im Modus all gibt es den Service "FE2 API Capabilities" auch, wenn nur ein Endpunkt aktiv ist
+	all := runChecks(config, "all")
+	if last := all[len(all)-1]; last.Endpoint != "capabilities" {
+		t.Errorf("all: last endpoint = %s, want capabilities", last.Endpoint)
+	}
+	single := runChecks(config, "mqtt")
+	if last := single[len(single)-1]; last.Endpoint == "capabilities" {
+		t.Error("single endpoint: capabilities was reported")
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+func evaluateAmwebs(config Config, amwebs []Amweb) []Result {
+	levels := activeThresholds(config.Thresholds, time.Now())
+	var results []Result
+	for _, amweb := range amwebs {
+		result := Result{
+			State:   okState(amweb.ConnectionState),
+			Reason:  okReason("connectionState", amweb.ConnectionState),
+			Name:    "AmWeb: " + amweb.Name,
+			Metrics: []Metric{levels.amwebConnections.apply(Metric{Name: "connection", Value: float64(amweb.ConnectionsCount)})},
+			Summary: fmt.Sprintf("Organisation: %s ConnectionType: %s", amweb.Organisation, amweb.ConnectionType),
+			item:    amweb,
+		}
+		// Mit Schwellwerten gilt der schlechtere Zustand aus connectionState und Anzahl der Verbindungen
+		if description := levels.amwebConnections.String(); description != "" {
+			result.State = worstState(result.State, Result{State: StateDynamic, Metrics: result.Metrics}.EffectiveState())
+			result.Reason += ", nbrOfWebSocketConnections gegen Schwellwerte " + description + levels.describeWindow()
+		}
+		results = append(results, result)
+	}
+	if config.AmwebOrganisations {
+		results = append(results, evaluateAmwebOrganisations(amwebs)...)
+	}
+	return results
+}
+
+func evaluateCloud(services []CloudService) []Result {
+	var results []Result
+	for _, service := range services {
+		results = append(results, Result{
+			State:   okState(service.State),
+			Reason:  okReason("state", service.State),
+			Name:    "FE2 Cloud: " + service.Name,
+			Summary: fmt.Sprintf("Status des %s Service in der FE2 Cloud", service.Name),
+			item:    service,
+		})
+	}
+	return results
+}
+
+func evaluateStatus(config Config, services Status) []Result {
+	levels := activeThresholds(config.Thresholds, time.Now())
+	return []Result{{
+		State:   StateDynamic,
+		Reason:  "nbrOfLoggedErrors gegen Schwellwerte " + levels.statusErrors.String() + levels.describeWindow(),
+		Name:    "FE2 Selfstatus",
+		Metrics: []Metric{levels.statusErrors.apply(Metric{Name: "errors", Value: float64(services.NbrOfLoggedErrors)})},
+		Summary: services.Message,
+		item:    services,
+	}}
+}
+
+func evaluateMqtt(services Mqtt) []Result {
+	return []Result{
+		{State: mqttState(services.Defaultbroker), Reason: fmt.Sprintf("defaultBroker %q", services.Defaultbroker), Name: "FE2 MQTT Defaultbroker", Summary: "Verbindung zum Default Broker", item: services},
+		{State: mqttState(services.Kubernetes), Reason: fmt.Sprintf("kubernetes %q", services.Kubernetes), Name: "FE2 MQTT Kubernetes", Summary: "Verbindung zum Kubernetes Cluster", item: services},
+	}
+}
+
+// mqttState bildet den Zustand eines MQTT Brokers auf einen checkmk Status ab
+func mqttState(state string) int {
+	switch state {
+	case "ERROR":
+		return 1
+	case "NOT_USED":
+		return 3
+	}
+	return 0
+}
+
+func evaluateInputs(inputs []InputServiceDetail) []Result {
+	var results []Result
+	for _, detailedInfo := range inputs {
+		if detailedInfo.Message == "" {
+			detailedInfo.Message = "No Message available"
+		}
+		results = append(results, Result{
+			State:   okState(detailedInfo.State),
+			Reason:  okReason("state", detailedInfo.State),
+			Name:    "FE2 Input: " + detailedInfo.Name,
+			Summary: detailedInfo.Message,
+			item:    detailedInfo,
+		})
+	}
+	return results
+}
+
+// AmwebOrganisation fasst die AMweb Geräte einer Organisation zusammen
+type AmwebOrganisation struct {
+	Name             string
+	Devices          int
+	Connected        int
+	ConnectionsCount int
+	ConnectionTypes  []string
+}
+
+// aggregateAmwebOrganisations gruppiert die AMweb Geräte nach Organisation
+func aggregateAmwebOrganisations(amwebs []Amweb) []AmwebOrganisation {
+	byName := make(map[string]*AmwebOrganisation)
+	for _, amweb := range amwebs {
+		name := amweb.Organisation
+		if name == "" {
+			name = "Ohne Organisation"
+		}
+		org, ok := byName[name]
+		if !ok {
+			org = &AmwebOrganisation{Name: name}
+			byName[name] = org
+		}
+		org.Devices++
+		if amweb.ConnectionState == "OK" {
+			org.Connected++
+		}
+		org.ConnectionsCount += amweb.ConnectionsCount
+		if amweb.ConnectionType != "" && !containsString(org.ConnectionTypes, amweb.ConnectionType) {
+			org.ConnectionTypes = append(org.ConnectionTypes, amweb.ConnectionType)
+		}
+	}
+
+	// Sortierte Ausgabe, damit die Services in checkmk stabil bleiben
+	var orgs []AmwebOrganisation
+	for _, org := range byName {
+		sort.Strings(org.ConnectionTypes)
+		orgs = append(orgs, *org)
+	}
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].Name < orgs[j].Name })
+	return orgs
+}
+
+// evaluateAmwebOrganisations liefert je Organisation einen aggregierten Service
+func evaluateAmwebOrganisations(amwebs []Amweb) []Result {
+	var results []Result
+	for _, org := range aggregateAmwebOrganisations(amwebs) {
+		orgStatus := 0
+		if org.Connected == 0 {
+			orgStatus = 2
+		}
+		connectionTypes := "-"
+		if len(org.ConnectionTypes) > 0 {
+			connectionTypes = strings.Join(org.ConnectionTypes, ", ")
+		}
+		results = append(results, Result{
+			State:  orgStatus,
+			Reason: fmt.Sprintf("%d verbundene Geräte", org.Connected),
+			Name:   "AmWeb Organisation: " + org.Name,
+			Metrics: []Metric{
+				{Name: "devices", Value: float64(org.Devices)},
+				{Name: "connected", Value: float64(org.Connected)},
+				{Name: "connections", Value: float64(org.ConnectionsCount)},
+			},
+			Summary: fmt.Sprintf("%d von %d Geräten verbunden ConnectionType: %s", org.Connected, org.Devices, connectionTypes),
+		})
+	}
+	return results
+}
+
+// okState liefert 0 (OK) für den FE2 Zustand "OK", sonst 1 (WARN)
+func okState(state string) int {
+	if state != "OK" {
+		return 1
+	}
+	return 0
+}
+
+// okReason beschreibt die Abbildung eines FE2 Zustands durch okState
+func okReason(field, state string) string {
+	return fmt.Sprintf("%s %q, OK erwartet", field, state)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
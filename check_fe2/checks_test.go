@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"checkmk_fe2/fe2sim"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestMain(m *testing.M) {
+	// Fehler werden in den Tests über EndpointResult geprüft
+	log.SetOutput(io.Discard)
+	// Der Pfad der Konfiguration erscheint in Hinweisen und muss für die Golden Files fest sein
+	os.Setenv("CHECK_FE2_CONFIG", "/etc/check_mk/check_fe2.yaml")
+	os.Exit(m.Run())
+}
+
+// newTestServer startet einen simulierten FE2 Server und liefert eine passende Konfiguration
+func newTestServer(t *testing.T, fixture fe2sim.Fixture) (*fe2sim.Simulator, Config) {
+	t.Helper()
+	simulator := fe2sim.New(fixture)
+	server := httptest.NewServer(simulator)
+	t.Cleanup(server.Close)
+	config := Config{
+		Token:   fixture.Token,
+		Timeout: time.Second,
+		ApiURL:  server.URL + fe2sim.BasePath,
+	}
+	return simulator, config
+}
+
+func loadFixture(t *testing.T, name string) fe2sim.Fixture {
+	t.Helper()
+	fixture, err := fe2sim.Load(filepath.Join("testdata", "fe2", name+".yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fixture
+}
+
+// assertGolden vergleicht die Ausgabe mit testdata/golden/<name>.txt
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	golden := filepath.Join("testdata", "golden", name+".txt")
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s\ngot:\n%s\nwant:\n%s", golden, got, want)
+	}
+}
+
+// responseTimes maskiert die Antwortzeiten des Services "FE2 API", damit die Golden Files stabil bleiben
+var responseTimes = regexp.MustCompile(`(response_time_\w+'?=)[0-9.]+`)
+
+func runCheckmk(config Config, command string) ([]byte, []EndpointResult) {
+	endpoints := runChecks(config, command)
+	var out bytes.Buffer
+	writeCheckmk(&out, endpoints)
+	return responseTimes.ReplaceAll(out.Bytes(), []byte("${1}<t>")), endpoints
+}
+
+func TestGoldenRecorded(t *testing.T) {
+	_, config := newTestServer(t, loadFixture(t, "recorded"))
+	for _, command := range []string{"input", "amweb", "cloud", "status", "mqtt", "all"} {
+		t.Run(command, func(t *testing.T) {
+			out, endpoints := runCheckmk(config, command)
+			for _, endpoint := range endpoints {
+				if endpoint.Err != nil {
+					t.Errorf("%s: unexpected error: %v", endpoint.Endpoint, endpoint.Err)
+				}
+			}
+			assertGolden(t, command, out)
+		})
+	}
+}
+
+func TestGoldenAmwebOrganisations(t *testing.T) {
+	_, config := newTestServer(t, loadFixture(t, "recorded"))
+	config.AmwebOrganisations = true
+	out, _ := runCheckmk(config, "amweb")
+	assertGolden(t, "amweb_organisations", out)
+}
+
+func TestGoldenNagios(t *testing.T) {
+	_, config := newTestServer(t, loadFixture(t, "recorded"))
+	var out bytes.Buffer
+	exitCode := writeNagios(&out, runChecks(config, "all"))
+	// Kubernetes NOT_USED wird als UNKNOWN abgebildet und ist damit der schlechteste Status
+	if exitCode != StateUnknown {
+		t.Errorf("exit code = %d, want %d", exitCode, StateUnknown)
+	}
+	assertGolden(t, "all_nagios", responseTimes.ReplaceAll(out.Bytes(), []byte("${1}<t>")))
+}
+
+func TestGoldenNagiosUnauthorized(t *testing.T) {
+	fixture := copyFixture(loadFixture(t, "recorded"))
+	fixture.Token = "other-token"
+	_, config := newTestServer(t, fixture)
+	config.Token = "test-token"
+	var out bytes.Buffer
+	if exitCode := writeNagios(&out, runChecks(config, "all")); exitCode != StateCrit {
+		t.Errorf("exit code = %d, want %d", exitCode, StateCrit)
+	}
+	// Jeder Endpunkt erscheint nur mit seinem Service, nicht zusätzlich mit dem Fehler der Anfrage
+	if strings.Contains(out.String(), "input: ") {
+		t.Errorf("raw error of input was reported in addition to its service\n%s", out.String())
+	}
+	assertGolden(t, "error_unauthorized_nagios", responseTimes.ReplaceAll(out.Bytes(), []byte("${1}<t>")))
+}
+
+// jsonTimes maskiert Laufzeiten und Antwortzeiten in der JSON Ausgabe
+var jsonTimes = regexp.MustCompile(`("durationMs": |"name": "response_time_\w+",\s+"value": )[0-9.e-]+`)
+
+func TestGoldenJSON(t *testing.T) {
+	fixture := copyFixture(loadFixture(t, "recorded"))
+	setEndpoint(&fixture, "mqtt", fe2sim.Response{Malformed: true})
+	_, config := newTestServer(t, fixture)
+	var out bytes.Buffer
+	start := time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)
+	if exitCode := writeJSON(&out, start, runChecks(config, "all")); exitCode != 0 {
+		t.Errorf("exit code = %d, want 0", exitCode)
+	}
+	assertGolden(t, "all_json", jsonTimes.ReplaceAll(out.Bytes(), []byte("${1}0")))
+}
+
+func TestGoldenErrors(t *testing.T) {
+	recorded := loadFixture(t, "recorded")
+	tests := []struct {
+		name    string
+		modify  func(f *fe2sim.Fixture)
+		timeout time.Duration
+		// wantErr enthält je Endpunkt einen Teil der erwarteten Fehlermeldung
+		wantErr map[string]string
+	}{
+		{
+			name:    "timeout",
+			modify:  func(f *fe2sim.Fixture) { setEndpoint(f, "amweb", fe2sim.Response{Delay: time.Second, Raw: "[]"}) },
+			timeout: 100 * time.Millisecond,
+			wantErr: map[string]string{"amweb": "Client.Timeout"},
+		},
+		{
+			name:    "unauthorized",
+			modify:  func(f *fe2sim.Fixture) { f.Token = "other-token" },
+			wantErr: map[string]string{"input": "401", "amweb": "401", "cloud": "401", "status": "401", "mqtt": "401"},
+		},
+		{
+			name: "forbidden",
+			modify: func(f *fe2sim.Fixture) {
+				setEndpoint(f, "cloud", fe2sim.Response{Status: http.StatusForbidden})
+			},
+			wantErr: map[string]string{"cloud": "403"},
+		},
+		{
+			name: "server_error",
+			modify: func(f *fe2sim.Fixture) {
+				setEndpoint(f, "status", fe2sim.Response{Status: http.StatusInternalServerError})
+			},
+			wantErr: map[string]string{"status": "500"},
+		},
+		{
+			name:    "input_detail_not_found",
+			modify:  func(f *fe2sim.Fixture) { delete(f.Endpoints, "input/6567a1f0c2e4a1b0d8f3e002") },
+			wantErr: map[string]string{"input": "404"},
+		},
+		{
+			name: "empty_lists",
+			modify: func(f *fe2sim.Fixture) {
+				setEndpoint(f, "input", fe2sim.Response{Raw: "[]"})
+				setEndpoint(f, "amweb", fe2sim.Response{Raw: "[]"})
+				setEndpoint(f, "cloud", fe2sim.Response{Raw: "[]"})
+			},
+		},
+		{
+			name:    "malformed_json",
+			modify:  func(f *fe2sim.Fixture) { setEndpoint(f, "mqtt", fe2sim.Response{Malformed: true}) },
+			wantErr: map[string]string{"mqtt": "error decoding JSON"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture := copyFixture(recorded)
+			tt.modify(&fixture)
+			_, config := newTestServer(t, fixture)
+			config.Token = recorded.Token
+			if tt.timeout > 0 {
+				config.Timeout = tt.timeout
+			}
+
+			out, endpoints := runCheckmk(config, "all")
+			for _, endpoint := range endpoints {
+				want, ok := tt.wantErr[endpoint.Endpoint]
+				switch {
+				case !ok && endpoint.Err != nil:
+					t.Errorf("%s: unexpected error: %v", endpoint.Endpoint, endpoint.Err)
+				case ok && endpoint.Err == nil:
+					t.Errorf("%s: expected error containing %q", endpoint.Endpoint, want)
+				case ok && !strings.Contains(endpoint.Err.Error(), want):
+					t.Errorf("%s: error %q does not contain %q", endpoint.Endpoint, endpoint.Err, want)
+				}
+			}
+			assertGolden(t, "error_"+tt.name, out)
+		})
+	}
+}
+
+func setEndpoint(f *fe2sim.Fixture, name string, response fe2sim.Response) {
+	f.Endpoints[name] = fe2sim.Endpoint{Response: response}
+}
+
+func copyFixture(f fe2sim.Fixture) fe2sim.Fixture {
+	endpoints := make(map[string]fe2sim.Endpoint, len(f.Endpoints))
+	for name, endpoint := range f.Endpoints {
+		endpoints[name] = endpoint
+	}
+	f.Endpoints = endpoints
+	return f
+}
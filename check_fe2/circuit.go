@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	circuitFile            = "circuit.json"
+	circuitServiceName     = "FE2 API Circuit Breaker"
+	defaultCircuitCooldown = 5 * time.Minute
+)
+
+// CircuitBreakerConfig schützt einen überlasteten FE2 Server vor weiteren Anfragen
+type CircuitBreakerConfig struct {
+	// Failures ist die Anzahl aufeinanderfolgender Fehler, nach der keine Anfragen mehr gesendet werden, 0 deaktiviert
+	Failures int `yaml:"failures"`
+	// Cooldown ist die Pause nach dem Öffnen, Standard sind 5 Minuten
+	Cooldown time.Duration `yaml:"cooldown"`
+}
+
+// CircuitOpenError wird statt einer Anfrage geliefert, solange der Circuit Breaker offen ist
+type CircuitOpenError struct {
+	Remaining time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return "circuit breaker open, next attempt in " + formatAge(e.Remaining)
+}
+
+// circuitState wird je FE2 Instanz im cache_dir gespeichert, damit die Pause über mehrere Läufe gilt
+type circuitState struct {
+	Failures  int       `json:"failures"`
+	OpenUntil time.Time `json:"openUntil,omitempty"`
+}
+
+// circuitBreaker zählt aufeinanderfolgende Fehler einer FE2 Instanz. Nach Ablauf der Pause
+// lässt er genau eine Anfrage als Probe durch (half-open) und schließt bei Erfolg wieder.
+type circuitBreaker struct {
+	mu       sync.Mutex
+	config   CircuitBreakerConfig
+	instance string
+	state    circuitState
+	probing  bool
+	// wasOpen gibt an, ob der Circuit Breaker zu Beginn des Laufs offen war
+	wasOpen bool
+}
+
+func loadCircuitBreaker(config Config) *circuitBreaker {
+	breaker := &circuitBreaker{config: config.CircuitBreaker, instance: config.instance()}
+	if breaker.config.Cooldown == 0 {
+		breaker.config.Cooldown = defaultCircuitCooldown
+	}
+	if states, err := readCircuitStates(config); err == nil {
+		breaker.state = states[breaker.instance]
+	} else if !errors.Is(err, os.ErrNotExist) {
+		config.logger().WithError(err).Warn("Error reading circuit breaker state")
+	}
+	breaker.wasOpen = breaker.open()
+	return breaker
+}
+
+func readCircuitStates(config Config) (map[string]circuitState, error) {
+	states := make(map[string]circuitState)
+	if config.CacheDir == "" {
+		return states, nil
+	}
+	data, err := os.ReadFile(filepath.Join(config.CacheDir, circuitFile))
+	if err != nil {
+		return states, err
+	}
+	return states, json.Unmarshal(data, &states)
+}
+
+// save schreibt den Zustand dieser Instanz in das cache_dir, andere Instanzen bleiben erhalten
+func (b *circuitBreaker) save(config Config) {
+	if config.CacheDir == "" {
+		return
+	}
+	states, _ := readCircuitStates(config)
+	b.mu.Lock()
+	if b.state.Failures == 0 {
+		delete(states, b.instance)
+	} else {
+		states[b.instance] = b.state
+	}
+	b.mu.Unlock()
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err == nil {
+		err = writeCacheFile(config, circuitFile, data)
+	}
+	if err != nil {
+		config.logger().WithError(err).Warn("Error writing circuit breaker state")
+	}
+}
+
+func (b *circuitBreaker) open() bool {
+	return b.state.Failures >= b.config.Failures
+}
+
+// allow prüft vor jeder Anfrage, ob sie gesendet werden darf
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open() {
+		return nil
+	}
+	if remaining := time.Until(b.state.OpenUntil); remaining > 0 {
+		return &CircuitOpenError{Remaining: remaining}
+	}
+	// Half-open: nur eine Probe, bis deren Ergebnis feststeht
+	if b.probing {
+		return &CircuitOpenError{}
+	}
+	b.probing = true
+	return nil
+}
+
+// record wertet das Ergebnis einer Anfrage aus
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !serverFailure(err) {
+		b.state = circuitState{}
+		return
+	}
+	b.state.Failures++
+	if b.open() {
+		b.state.OpenUntil = time.Now().Add(b.config.Cooldown)
+	}
+}
+
+// serverFailure unterscheidet Überlastung und Nichterreichbarkeit von FE2 von anderen Fehlern.
+// Eine Antwort mit 401 oder 404 zeigt, dass der Server arbeitet.
+func serverFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= http.StatusInternalServerError || apiErr.StatusCode == http.StatusTooManyRequests
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// result liefert den Service "FE2 API Circuit Breaker", UNKNOWN solange keine Anfragen gesendet werden
+func (b *circuitBreaker) result() Result {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	result := Result{Name: circuitServiceName, Reason: fmt.Sprintf("offen nach %d aufeinanderfolgenden Fehlern für %s", b.config.Failures, b.config.Cooldown)}
+	switch {
+	case b.open():
+		result.State = StateUnknown
+		result.Summary = fmt.Sprintf("Offen nach %d aufeinanderfolgenden Fehlern, Anfragen an FE2 ausgesetzt, nächster Versuch in %s", b.state.Failures, formatAge(time.Until(b.state.OpenUntil)))
+	case b.wasOpen:
+		result.State = StateOK
+		result.Summary = "Wieder geschlossen, die Probe-Anfrage war erfolgreich"
+	case b.state.Failures > 0:
+		result.State = StateOK
+		result.Summary = fmt.Sprintf("Geschlossen, %d von %d aufeinanderfolgenden Fehlern", b.state.Failures, b.config.Failures)
+	default:
+		result.State = StateOK
+		result.Summary = "Geschlossen"
+	}
+	return result
+}
+
+// markCircuitOpen ergänzt Endpunkte, die wegen des offenen Circuit Breakers nicht abgefragt wurden, um einen UNKNOWN Service
+func markCircuitOpen(endpoints []EndpointResult) {
+	for i, endpoint := range endpoints {
+		var openErr *CircuitOpenError
+		if !errors.As(endpoint.Err, &openErr) {
+			continue
+		}
+		endpoints[i].Results = append(endpoints[i].Results, Result{
+			State:   StateUnknown,
+			Name:    "FE2 API Endpoint: " + endpoint.Endpoint,
+			Reason:  "Circuit Breaker offen",
+			Summary: fmt.Sprintf("Abfrage ausgesetzt, siehe Service %s (nächster Versuch in %s)", circuitServiceName, formatAge(openErr.Remaining)),
+		})
+	}
+}
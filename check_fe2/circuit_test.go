@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"checkmk_fe2/fe2sim"
+)
+
+// totalRequests zählt die Anfragen an alle Endpunkte des Simulators
+func totalRequests(simulator *fe2sim.Simulator, fixture fe2sim.Fixture) int {
+	total := 0
+	for name := range fixture.Endpoints {
+		total += simulator.Requests(name)
+	}
+	return total
+}
+
+func circuitResult(t *testing.T, endpoints []EndpointResult) Result {
+	t.Helper()
+	for _, endpoint := range endpoints {
+		if endpoint.Endpoint == "circuit" {
+			return endpoint.Results[0]
+		}
+	}
+	t.Fatal("circuit breaker service missing")
+	return Result{}
+}
+
+// expireCircuit setzt das Ende der Pause in die Vergangenheit
+func expireCircuit(t *testing.T, config Config) {
+	t.Helper()
+	states, err := readCircuitStates(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := states[config.instance()]
+	state.OpenUntil = time.Now().Add(-time.Second)
+	states[config.instance()] = state
+	data, _ := json.Marshal(states)
+	if err := os.WriteFile(filepath.Join(config.CacheDir, circuitFile), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	healthy := loadFixture(t, "recorded")
+	overloaded := copyFixture(healthy)
+	for name := range overloaded.Endpoints {
+		setEndpoint(&overloaded, name, fe2sim.Response{Status: http.StatusServiceUnavailable})
+	}
+	simulator, config := newTestServer(t, overloaded)
+	config.CacheDir = t.TempDir()
+	config.CircuitBreaker = CircuitBreakerConfig{Failures: 2, Cooldown: time.Hour}
+
+	// Nach zwei Fehlern werden die übrigen Endpunkte nicht mehr abgefragt
+	endpoints := runChecks(config, "all")
+	if got := totalRequests(simulator, overloaded); got != 2 {
+		t.Errorf("first run sent %d requests, want 2", got)
+	}
+	if result := circuitResult(t, endpoints); result.State != StateUnknown || !strings.Contains(result.Summary, "nächster Versuch in") {
+		t.Errorf("got %d %q, want UNKNOWN with remaining cool-down", result.State, result.Summary)
+	}
+	out, _ := runCheckmk(config, "all")
+	if got := totalRequests(simulator, overloaded); got != 2 {
+		t.Errorf("open circuit sent %d requests, want none", got-2)
+	}
+	if !strings.Contains(string(out), `3 "FE2 API Endpoint: status" - Abfrage ausgesetzt`) {
+		t.Errorf("skipped endpoint not reported as UNKNOWN:\n%s", out)
+	}
+
+	// Half-open: nach der Pause nur eine Probe, die erneut fehlschlägt
+	expireCircuit(t, config)
+	runChecks(config, "all")
+	if got := totalRequests(simulator, overloaded); got != 3 {
+		t.Errorf("half-open circuit sent %d requests, want 1", got-2)
+	}
+
+	// Ist die Probe erfolgreich, wird der Circuit Breaker wieder geschlossen
+	expireCircuit(t, config)
+	simulator.SetFixture(healthy)
+	endpoints = runChecks(config, "all")
+	if result := circuitResult(t, endpoints); result.State != StateOK || !strings.Contains(result.Summary, "Wieder geschlossen") {
+		t.Errorf("got %d %q, want closed after successful probe", result.State, result.Summary)
+	}
+	for _, endpoint := range endpoints {
+		if endpoint.Err != nil {
+			t.Errorf("%s: unexpected error: %v", endpoint.Endpoint, endpoint.Err)
+		}
+	}
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	_, config := newTestServer(t, loadFixture(t, "recorded"))
+	config.Token = "wrong-token"
+	config.CircuitBreaker = CircuitBreakerConfig{Failures: 1}
+
+	// 401 zeigt, dass FE2 antwortet, der Circuit Breaker bleibt geschlossen
+	endpoints := runChecks(config, "all")
+	if _, ok := authError(endpoints[0].Err); !ok {
+		t.Fatalf("expected 401, got %v", endpoints[0].Err)
+	}
+	if result := circuitResult(t, endpoints); result.State != StateOK {
+		t.Errorf("got %d %q, want OK", result.State, result.Summary)
+	}
+}
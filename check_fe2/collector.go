@@ -0,0 +1,124 @@
+package main
+
+import "fmt"
+
+// Collector fragt einen Endpunkt der Monitoring-Schnittstelle ab und wertet die Antwort zu Services aus.
+// Weitere Collectors können in einer eigenen Datei mit RegisterCollector in init() ergänzt werden.
+type Collector interface {
+	// Name ist der Name des Endpunkts, z.B. "cloud", und zugleich das Kommando für check_fe2
+	Name() string
+	// Fetch ruft die Daten ab. Liefert Fetch Daten zusammen mit einem Fehler, werden diese trotzdem ausgewertet.
+	Fetch(config Config) (interface{}, error)
+	// Evaluate erzeugt die Services aus den Daten von Fetch
+	Evaluate(config Config, data interface{}) []Result
+}
+
+// collectors enthält die registrierten Collectors in der Reihenfolge der Ausgabe
+var collectors = []Collector{
+	endpointCollector[[]InputServiceDetail]{
+		name:  "input",
+		fetch: fetchInputs,
+		evaluate: func(config Config, inputs []InputServiceDetail) []Result {
+			return evaluateInputs(inputs)
+		},
+		// Bereits abgerufene Eingänge werden auch bei einem Fehler ausgegeben
+		partial: true,
+	},
+	endpointCollector[[]Amweb]{
+		name:     "amweb",
+		fetch:    fetchAmwebs,
+		evaluate: evaluateAmwebs,
+	},
+	endpointCollector[[]CloudService]{
+		name:  "cloud",
+		fetch: fetchCloud,
+		evaluate: func(config Config, services []CloudService) []Result {
+			return evaluateCloud(services)
+		},
+	},
+	endpointCollector[Status]{
+		name:     "status",
+		fetch:    fetchStatus,
+		evaluate: evaluateStatus,
+	},
+	endpointCollector[Mqtt]{
+		name:  "mqtt",
+		fetch: fetchMqtt,
+		evaluate: func(config Config, mqtt Mqtt) []Result {
+			return evaluateMqtt(mqtt)
+		},
+	},
+}
+
+// configCollectors liefert die registrierten und die in der Konfiguration definierten Collectors
+func configCollectors(config Config) []Collector {
+	all := append([]Collector{}, collectors...)
+	for _, endpoint := range config.CustomEndpoints {
+		all = append(all, customCollector{endpoint: endpoint})
+	}
+	return all
+}
+
+// RegisterCollector fügt einen Collector hinzu, der Name muss eindeutig sein
+func RegisterCollector(collector Collector) {
+	if findCollector(collector.Name()) != nil {
+		panic(fmt.Sprintf("collector %q registered twice", collector.Name()))
+	}
+	collectors = append(collectors, collector)
+}
+
+func findCollector(name string) Collector {
+	for _, collector := range collectors {
+		if collector.Name() == name {
+			return collector
+		}
+	}
+	return nil
+}
+
+// collectorNames liefert die Collectors eines Aufrufs: bei "all" alle in der Konfiguration aktivierten,
+// sonst nur den angegebenen, auch wenn er deaktiviert ist
+func collectorNames(config Config, command string) []string {
+	var names []string
+	for _, collector := range configCollectors(config) {
+		name := collector.Name()
+		if (command == "all" && config.endpointEnabled(name)) || command == name {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// runCollector ruft einen Collector ab und wertet die Daten aus
+func runCollector(config Config, collector Collector) (interface{}, []Result, error) {
+	data, err := collector.Fetch(config)
+	if data == nil {
+		return nil, nil, err
+	}
+	return data, collector.Evaluate(config, data), err
+}
+
+// endpointCollector ist ein Collector für einen Endpunkt mit festem Datentyp
+type endpointCollector[T any] struct {
+	name     string
+	fetch    func(config Config) (T, error)
+	evaluate func(config Config, data T) []Result
+	// partial wertet bei einem Fehler die bis dahin abgerufenen Daten aus
+	partial bool
+}
+
+func (c endpointCollector[T]) Name() string {
+	return c.name
+}
+
+func (c endpointCollector[T]) Fetch(config Config) (interface{}, error) {
+	data, err := c.fetch(config)
+	if err != nil && !c.partial {
+		return nil, err
+	}
+	return data, err
+}
+
+func (c endpointCollector[T]) Evaluate(config Config, data interface{}) []Result {
+	return c.evaluate(config, data.(T))
+}
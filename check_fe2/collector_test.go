@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// versionCollector ist ein Collector eines Drittanbieters ohne eigene Anfrage an FE2
+type versionCollector struct{}
+
+func (versionCollector) Name() string { return "version" }
+
+func (versionCollector) Fetch(config Config) (interface{}, error) {
+	return "2.38", nil
+}
+
+func (versionCollector) Evaluate(config Config, data interface{}) []Result {
+	return []Result{{State: StateOK, Name: "FE2 Version", Summary: data.(string)}}
+}
+
+func TestRegisterCollector(t *testing.T) {
+	registered := collectors
+	defer func() { collectors = registered }()
+	RegisterCollector(versionCollector{})
+
+	_, config := newTestServer(t, loadFixture(t, "recorded"))
+	config.Endpoints = map[string]bool{"mqtt": false, "amweb": false}
+	names := collectorNames(config, "all")
+	want := []string{"input", "cloud", "status", "version"}
+	if len(names) != len(want) {
+		t.Fatalf("names = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("names = %v, want %v", names, want)
+		}
+	}
+	// Explizit angegebene Endpunkte werden auch abgefragt, wenn sie deaktiviert sind
+	if names := collectorNames(config, "mqtt"); len(names) != 1 {
+		t.Errorf("mqtt command: names = %v", names)
+	}
+
+	var found bool
+	for _, endpoint := range runChecks(config, "all") {
+		if endpoint.Endpoint == "amweb" || endpoint.Endpoint == "mqtt" {
+			t.Errorf("disabled endpoint %s was checked", endpoint.Endpoint)
+		}
+		if endpoint.Endpoint == "version" {
+			found = len(endpoint.Results) == 1 && endpoint.Results[0].Summary == "2.38"
+		}
+	}
+	if !found {
+		t.Error("registered collector was not run")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a duplicate collector did not panic")
+		}
+	}()
+	RegisterCollector(versionCollector{})
+}
+
+func TestEndpointCollectorPartial(t *testing.T) {
+	failure := errors.New("timeout")
+	collector := endpointCollector[[]string]{
+		name:     "partial",
+		fetch:    func(Config) ([]string, error) { return []string{"a"}, failure },
+		evaluate: func(Config, []string) []Result { return []Result{{Name: "a"}} },
+	}
+	if data, results, err := runCollector(Config{}, collector); data != nil || results != nil || err != failure {
+		t.Errorf("got %v %v %v, want only the error", data, results, err)
+	}
+	collector.partial = true
+	if _, results, err := runCollector(Config{}, collector); len(results) != 1 || err != failure {
+		t.Errorf("got %v %v, want partial results with the error", results, err)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+type Config struct {
+	Hostname string `yaml:"hostname"`
+	Token    string `yaml:"token"`
+	Port     string `yaml:"port"`
+	Protocol string `yaml:"protocol"`
+	// AmwebOrganisations aktiviert einen zusätzlichen Service je Organisation
+	AmwebOrganisations bool `yaml:"amweb_organisations"`
+	// Timeout begrenzt die Dauer einer Anfrage an FE2, Standard sind 10 Sekunden
+	Timeout time.Duration `yaml:"timeout"`
+	// CacheDir enthält zwischengespeicherte Daten wie die unterstützten Endpunkte
+	CacheDir string `yaml:"cache_dir"`
+	// CapabilitiesTTL gibt an, wie lange die erkannten Endpunkte gültig sind, Standard sind 24 Stunden
+	CapabilitiesTTL time.Duration `yaml:"capabilities_ttl"`
+	// API enthält die Schwellwerte des Services "FE2 API"
+	API APILevels `yaml:"api"`
+	// Plugin enthält die Schwellwerte des Services "FE2 Plugin"
+	Plugin PluginLevels `yaml:"plugin"`
+	// Endpoints aktiviert oder deaktiviert einzelne Endpunkte für "all", z.B. mqtt: false
+	Endpoints map[string]bool `yaml:"endpoints"`
+	// CustomEndpoints definiert zusätzliche Endpunkte mit JSON-Pfaden
+	CustomEndpoints []CustomEndpoint `yaml:"custom_endpoints"`
+	// Rules berechnen den Zustand der Services je Endpunkt aus Ausdrücken, z.B. rules: {amweb: [...]}
+	Rules map[string][]Rule `yaml:"rules"`
+	// OverridesFile enthält Bestätigungen und erwartete Ausfälle, Standard ist check_fe2_overrides.yaml neben der Konfiguration
+	OverridesFile string `yaml:"overrides_file"`
+	// Thresholds enthält Schwellwerte mit abweichenden Werten je Zeitfenster
+	Thresholds ThresholdConfig `yaml:"thresholds"`
+	// InputDetails legt fest, wann die Details der Eingänge abgefragt werden
+	InputDetails InputDetailsConfig `yaml:"input_details"`
+	// CircuitBreaker setzt die Anfragen an einen überlasteten FE2 Server aus
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker"`
+	// Proxy legt den HTTP-Proxy fest, Standard sind HTTP_PROXY, HTTPS_PROXY und NO_PROXY
+	Proxy ProxyConfig `yaml:"proxy"`
+	// Log steuert Level, Format und Ziel der Logs
+	Log LogConfig `yaml:"log"`
+	// SchemaCheck meldet neue, fehlende oder geänderte Felder in den Antworten von FE2
+	SchemaCheck bool `yaml:"schema_check"`
+	ApiURL      string
+	// Transport ersetzt den HTTP-Transport, z.B. für --record und --replay
+	Transport http.RoundTripper `yaml:"-"`
+
+	schema   *schemaReport
+	requests *requestLog
+	breaker  *circuitBreaker
+	// isolated trennt einen Lauf mit --replay vom Zustand der echten Instanz
+	isolated bool
+}
+
+const (
+	defaultTimeout         = 10 * time.Second
+	defaultCapabilitiesTTL = 24 * time.Hour
+)
+
+// loadConfig liest die Konfiguration und setzt die Basis-URL der Monitoring-Schnittstelle
+func loadConfig() Config {
+	config, err := tryLoadConfig()
+	if err != nil {
+		log.WithError(err).Fatal("Error reading config file")
+	}
+	return config
+}
+
+// tryLoadConfig arbeitet wie loadConfig, liefert Fehler aber zurück, statt das Programm zu beenden
+func tryLoadConfig() (Config, error) {
+	config, err := readConfigFile(getConfigFilePath())
+	if err != nil {
+		return config, err
+	}
+	if err := validateCustomEndpoints(config.CustomEndpoints); err != nil {
+		return config, err
+	}
+	if err := validateRules(config); err != nil {
+		return config, err
+	}
+	if err := validateThresholds(config.Thresholds); err != nil {
+		return config, err
+	}
+	if err := validateInputDetails(config.InputDetails); err != nil {
+		return config, err
+	}
+	if config.Timeout == 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.CacheDir == "" {
+		config.CacheDir = getCacheDir()
+	}
+	if config.CapabilitiesTTL == 0 {
+		config.CapabilitiesTTL = defaultCapabilitiesTTL
+	}
+	config.ApiURL = config.Protocol + "://" + config.Hostname + ":" + config.Port + "/rest/monitoring/"
+	return config, nil
+}
+
+func readConfigFile(filename string) (Config, error) {
+	var config Config
+	// YAML-Datei öffnen
+	file, err := os.Open(filename)
+	if err != nil {
+		return config, fmt.Errorf("error opening config file: %w", err)
+	}
+	defer file.Close()
+
+	// YAML-Datei parsen
+	decoder := yaml.NewDecoder(file)
+	if err := decoder.Decode(&config); err != nil {
+		return config, fmt.Errorf("error decoding config file: %w", err)
+	}
+
+	return config, nil
+}
+func getConfigFilePath() string {
+	// Ein abweichender Pfad kann über CHECK_FE2_CONFIG gesetzt werden
+	if path := os.Getenv("CHECK_FE2_CONFIG"); path != "" {
+		return path
+	}
+	if runtime.GOOS != "windows" {
+		// Unter Linux liegt die Konfiguration im Konfigurationsverzeichnis des Agents
+		return filepath.Join(agentConfigDir(), "check_fe2.yaml")
+	}
+
+	// Pfad zum Ordner %ProgramData%\checkmk\agent\local
+	agentLocalFolder := filepath.Join(os.Getenv("ProgramData"), "checkmk", "agent", "local")
+
+	// Pfad zur Konfigurationsdatei im angegebenen Ordner
+	configFilePath := filepath.Join(agentLocalFolder, "config.yaml")
+
+	return configFilePath
+}
+
+func getCacheDir() string {
+	if runtime.GOOS != "windows" {
+		return filepath.Join(envOrDefault("MK_VARDIR", "/var/lib/check_mk_agent"), "cache", "check_fe2")
+	}
+	// Pfad zum Ordner %ProgramData%\checkmk\agent\tmp\check_fe2
+	return filepath.Join(os.Getenv("ProgramData"), "checkmk", "agent", "tmp", "check_fe2")
+}
+
+// agentConfigDir liefert das Konfigurationsverzeichnis des Linux Agents
+func agentConfigDir() string {
+	return envOrDefault("MK_CONFDIR", "/etc/check_mk")
+}
+
+func envOrDefault(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// endpointEnabled prüft, ob ein Endpunkt abgefragt werden soll, ohne Eintrag ist er aktiviert
+func (config Config) endpointEnabled(name string) bool {
+	enabled, ok := config.Endpoints[name]
+	return !ok || enabled
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// configCommand verarbeitet check_fe2 config <Unterbefehl>
+func configCommand(args []string, in io.Reader, out io.Writer) int {
+	if len(args) == 0 || args[0] != "init" {
+		log.Fatal("Usage: check_fe2 config init [--hostname HOST] [--port PORT] [--protocol http|https] [--token TOKEN]")
+	}
+	return configInit(args[1:], in, out)
+}
+
+// configInit fragt die Verbindungsdaten ab, testet sie gegen FE2 und schreibt die config.yaml (check_fe2 config init).
+// Werte, die als Parameter übergeben werden, werden nicht abgefragt.
+func configInit(args []string, in io.Reader, out io.Writer) int {
+	flags := flag.NewFlagSet("config init", flag.ExitOnError)
+	hostname := flags.String("hostname", "", "FE2 hostname or IP address")
+	port := flags.String("port", "", "FE2 port")
+	protocol := flags.String("protocol", "", "http or https")
+	token := flags.String("token", "", "token of the FE2 monitoring plugin")
+	path := flags.String("path", getConfigFilePath(), "config file to write")
+	force := flags.Bool("force", false, "overwrite an existing config file and write it even if the connection test fails")
+	skipTest := flags.Bool("skip-test", false, "do not test the connection")
+	flags.Parse(args)
+
+	if _, err := os.Stat(*path); err == nil && !*force {
+		fmt.Fprintf(out, "%s existiert bereits, zum Überschreiben --force angeben\n", *path)
+		return 1
+	}
+
+	reader := bufio.NewReader(in)
+	config := Config{
+		Hostname: prompt(reader, out, "Hostname des FE2 Servers", *hostname, "127.0.0.1"),
+		Port:     prompt(reader, out, "Port", *port, "83"),
+		Protocol: prompt(reader, out, "Protokoll (http/https)", *protocol, "http"),
+		Token:    prompt(reader, out, "Token aus dem Monitoring Plugin", *token, ""),
+		Timeout:  defaultTimeout,
+	}
+	if err := validateConfig(config); err != nil {
+		fmt.Fprintln(out, "Ungültige Konfiguration:", err)
+		return 1
+	}
+	config.ApiURL = config.Protocol + "://" + config.Hostname + ":" + config.Port + "/rest/monitoring/"
+
+	if !*skipTest {
+		fmt.Fprintln(out)
+		if diagnose(config, out) != StateOK && !*force {
+			fmt.Fprintln(out, "Die Konfiguration wurde nicht geschrieben, zum Schreiben trotz Fehlern --force angeben")
+			return 1
+		}
+	}
+
+	if err := writeConfigFile(*path, config); err != nil {
+		fmt.Fprintln(out, "Fehler beim Schreiben der Konfiguration:", err)
+		return 1
+	}
+	fmt.Fprintf(out, "Konfiguration geschrieben: %s\n", *path)
+	return 0
+}
+
+// prompt liefert den übergebenen Wert oder fragt ihn mit Vorgabewert ab
+func prompt(reader *bufio.Reader, out io.Writer, label, value, fallback string) string {
+	if value != "" {
+		return value
+	}
+	if fallback != "" {
+		fmt.Fprintf(out, "%s [%s]: ", label, fallback)
+	} else {
+		fmt.Fprintf(out, "%s: ", label)
+	}
+	line, _ := reader.ReadString('\n')
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return fallback
+	}
+	return line
+}
+
+// validateConfig prüft die Verbindungsdaten einer Konfiguration
+func validateConfig(config Config) error {
+	if config.Hostname == "" || strings.ContainsAny(config.Hostname, "/: ") {
+		return fmt.Errorf("hostname %q is invalid", config.Hostname)
+	}
+	if port, err := strconv.Atoi(config.Port); err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("port %q is invalid", config.Port)
+	}
+	if config.Protocol != "http" && config.Protocol != "https" {
+		return fmt.Errorf("protocol %q is invalid, use http or https", config.Protocol)
+	}
+	if config.Token == "" {
+		return fmt.Errorf("token is empty")
+	}
+	return nil
+}
+
+// writeConfigFile schreibt die Verbindungsdaten als config.yaml, die Datei ist nur für den Besitzer lesbar
+func writeConfigFile(path string, config Config) error {
+	content := fmt.Sprintf("# erstellt von check_fe2 config init am %s\nhostname: %s\nport: %s\nprotocol: %s\ntoken: %s\n",
+		time.Now().Format("2006-01-02"), config.Hostname, yamlQuote(config.Port), config.Protocol, yamlQuote(config.Token))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return err
+	}
+	// WriteFile setzt die Rechte nur beim Anlegen der Datei
+	return os.Chmod(path, 0600)
+}
+
+// yamlQuote setzt einen Wert in Anführungszeichen, damit er als String gelesen wird
+func yamlQuote(value string) string {
+	return strconv.Quote(value)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestConfigInit(t *testing.T) {
+	_, config := newTestServer(t, loadFixture(t, "recorded"))
+	serverURL, err := url.Parse(config.ApiURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "local", "config.yaml")
+
+	// Hostname und Port als Parameter, Protokoll (Vorgabe) und Token über die Eingabe
+	in := strings.NewReader("\ntest-token\n")
+	var out bytes.Buffer
+	args := []string{"--hostname", serverURL.Hostname(), "--port", serverURL.Port(), "--path", path}
+	if exitCode := configInit(args, in, &out); exitCode != 0 {
+		t.Fatalf("exit code = %d\n%s", exitCode, out.String())
+	}
+
+	written := loadConfigFile(t, path)
+	if written.Hostname != serverURL.Hostname() || written.Port != serverURL.Port() || written.Protocol != "http" || written.Token != "test-token" {
+		t.Errorf("unexpected config %+v", written)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("permissions = %v, want 0600", info.Mode().Perm())
+	}
+
+	// Eine vorhandene Datei wird ohne --force nicht überschrieben
+	if exitCode := configInit(append(args, "--token", "other"), strings.NewReader(""), &out); exitCode == 0 {
+		t.Error("existing config was overwritten without --force")
+	}
+}
+
+func TestConfigInitConnectionFailure(t *testing.T) {
+	_, config := newTestServer(t, loadFixture(t, "recorded"))
+	serverURL, err := url.Parse(config.ApiURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	args := []string{"--hostname", serverURL.Hostname(), "--port", serverURL.Port(), "--protocol", "http", "--token", "wrong-token", "--path", path}
+
+	var out bytes.Buffer
+	if exitCode := configInit(args, strings.NewReader(""), &out); exitCode == 0 {
+		t.Errorf("config with invalid token was accepted\n%s", out.String())
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("config was written although the connection test failed")
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CustomEndpoint ist ein in der Konfiguration definierter Endpunkt (custom_endpoints).
+// Neue Endpunkte von FE2 können so ohne neue Version des Plugins überwacht werden.
+type CustomEndpoint struct {
+	// Name des Collectors und Kommandos, z.B. license
+	Name string `yaml:"name"`
+	// Path ist der Pfad unter /rest/monitoring/, Standard ist Name
+	Path string `yaml:"path"`
+	// Items ist der JSON-Pfad zur Liste der Einträge, z.B. $.licenses[*]. Ohne Angabe ist die Antwort
+	// selbst die Liste bzw. bei einem Objekt der einzige Eintrag.
+	Items string `yaml:"items"`
+	// Service ist der Name des Services je Eintrag mit Platzhaltern, z.B. "FE2 Lizenz: {name}"
+	Service string `yaml:"service"`
+	// State ist der JSON-Pfad zum Zustand im Eintrag
+	State string `yaml:"state"`
+	// States bildet Werte des Zustands auf checkmk ab, ohne Angabe ist OK = 0 und alles andere WARN.
+	// Zahlen von 0 bis 3 werden ohne Abbildung direkt übernommen.
+	States map[string]int `yaml:"states"`
+	// Summary ist der Text des Services mit Platzhaltern, z.B. "{message}"
+	Summary string         `yaml:"summary"`
+	Metrics []CustomMetric `yaml:"metrics"`
+}
+
+// CustomMetric ist eine Metrik eines konfigurierten Endpunkts
+type CustomMetric struct {
+	Name string `yaml:"name"`
+	// Value ist der JSON-Pfad zum Wert im Eintrag
+	Value string `yaml:"value"`
+	// Warn und Crit setzen Schwellwerte, der Zustand wird dann auch aus der Metrik berechnet
+	Warn *float64 `yaml:"warn"`
+	Crit *float64 `yaml:"crit"`
+}
+
+// placeholder findet Platzhalter wie {name} oder {$.details.message} in Service und Summary
+var placeholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// reservedNames sind Unterbefehle und Endpunkte der eigenen Services, ein konfigurierter Endpunkt
+// mit diesem Namen könnte nicht aufgerufen werden oder würde in der JSON-Ausgabe mit ihnen kollidieren
+var reservedNames = []string{
+	"all", "serve", "explain", "ack", "diagnose", "config", "install", "uninstall",
+	"plugin", "api", "authentication", "circuit", "schema", "capabilities",
+}
+
+// validateCustomEndpoints prüft die konfigurierten Endpunkte beim Lesen der Konfiguration
+func validateCustomEndpoints(endpoints []CustomEndpoint) error {
+	seen := make(map[string]bool)
+	for _, endpoint := range endpoints {
+		switch {
+		case endpoint.Name == "" || strings.ContainsAny(endpoint.Name, " /"):
+			return fmt.Errorf("custom endpoint name %q is invalid", endpoint.Name)
+		case containsString(reservedNames, endpoint.Name):
+			return fmt.Errorf("custom endpoint name %q is reserved", endpoint.Name)
+		case findCollector(endpoint.Name) != nil || seen[endpoint.Name]:
+			return fmt.Errorf("custom endpoint %q is defined twice", endpoint.Name)
+		case endpoint.Service == "":
+			return fmt.Errorf("custom endpoint %q: service is missing", endpoint.Name)
+		}
+		seen[endpoint.Name] = true
+		paths := []string{endpoint.Items, endpoint.State}
+		for _, match := range placeholder.FindAllStringSubmatch(endpoint.Service+endpoint.Summary, -1) {
+			paths = append(paths, match[1])
+		}
+		for _, metric := range endpoint.Metrics {
+			if metric.Name == "" || metric.Value == "" {
+				return fmt.Errorf("custom endpoint %q: metric needs name and value", endpoint.Name)
+			}
+			if (metric.Warn == nil) != (metric.Crit == nil) {
+				return fmt.Errorf("custom endpoint %q: metric %q needs both warn and crit", endpoint.Name, metric.Name)
+			}
+			paths = append(paths, metric.Value)
+		}
+		for value, state := range endpoint.States {
+			if state < StateOK || state > StateUnknown {
+				return fmt.Errorf("custom endpoint %q: state %d for %q must be between 0 and 3", endpoint.Name, state, value)
+			}
+		}
+		for _, path := range paths {
+			if _, err := parseJSONPath(path); err != nil {
+				return fmt.Errorf("custom endpoint %q: %w", endpoint.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// customCollector fragt einen konfigurierten Endpunkt ab
+type customCollector struct {
+	endpoint CustomEndpoint
+}
+
+func (c customCollector) Name() string {
+	return c.endpoint.Name
+}
+
+func (c customCollector) Fetch(config Config) (interface{}, error) {
+	path := c.endpoint.Path
+	if path == "" {
+		path = c.endpoint.Name
+	}
+	var data interface{}
+	if err := apiGet(config, strings.Trim(path, "/"), &data); err != nil {
+		return nil, err
+	}
+	if c.endpoint.Items == "" {
+		if list, ok := data.([]interface{}); ok {
+			return list, nil
+		}
+		return []interface{}{data}, nil
+	}
+	items, err := evalJSONPath(data, c.endpoint.Items)
+	if err != nil {
+		return nil, err
+	}
+	// Zeigt der Pfad auf eine Liste, sind deren Elemente die Einträge
+	if len(items) == 1 {
+		if list, ok := items[0].([]interface{}); ok {
+			items = list
+		}
+	}
+	return items, nil
+}
+
+func (c customCollector) Evaluate(config Config, data interface{}) []Result {
+	var results []Result
+	for _, item := range data.([]interface{}) {
+		results = append(results, c.evaluateItem(item))
+	}
+	return results
+}
+
+func (c customCollector) evaluateItem(item interface{}) Result {
+	result := Result{
+		State:   StateOK,
+		Name:    expandPlaceholders(c.endpoint.Service, item),
+		Summary: expandPlaceholders(c.endpoint.Summary, item),
+		item:    item,
+	}
+	levels := false
+	for _, metric := range c.endpoint.Metrics {
+		value, ok := lookupJSONPath(item, metric.Value)
+		number, isNumber := value.(float64)
+		if !ok || !isNumber {
+			continue
+		}
+		m := Metric{Name: metric.Name, Value: number}
+		if metric.Warn != nil && metric.Crit != nil {
+			m.Levels, m.Warn, m.Crit = true, *metric.Warn, *metric.Crit
+			levels = true
+		}
+		result.Metrics = append(result.Metrics, m)
+	}
+	if levels {
+		result.State = StateDynamic
+	}
+	if c.endpoint.State == "" {
+		return result
+	}
+
+	value, ok := lookupJSONPath(item, c.endpoint.State)
+	if !ok {
+		result.State = StateUnknown
+		result.Reason = c.endpoint.State + " fehlt"
+		return result
+	}
+	state := c.mapState(value)
+	result.Reason = fmt.Sprintf("%s %q", c.endpoint.State, formatJSONValue(value))
+	if levels {
+		// Der schlechtere Zustand aus Feld und Schwellwerten gilt
+		state = worstState(state, result.EffectiveState())
+	}
+	result.State = state
+	return result
+}
+
+// mapState bildet den Wert des Zustandsfelds auf einen checkmk Zustand ab
+func (c customCollector) mapState(value interface{}) int {
+	text := formatJSONValue(value)
+	if state, ok := c.endpoint.States[text]; ok {
+		return state
+	}
+	if len(c.endpoint.States) > 0 {
+		return StateUnknown
+	}
+	if number, ok := value.(float64); ok && number >= StateOK && number <= StateUnknown && number == float64(int(number)) {
+		return int(number)
+	}
+	return okState(text)
+}
+
+// expandPlaceholders ersetzt {pfad} durch die Werte des Eintrags, fehlende Werte durch -
+func expandPlaceholders(template string, item interface{}) string {
+	return placeholder.ReplaceAllStringFunc(template, func(match string) string {
+		value, _ := lookupJSONPath(item, match[1:len(match)-1])
+		return formatJSONValue(value)
+	})
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"checkmk_fe2/fe2sim"
+)
+
+func TestEvalJSONPath(t *testing.T) {
+	data := map[string]interface{}{
+		"data": map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"name": "a", "state": "OK"},
+				map[string]interface{}{"name": "b", "state": "ERROR"},
+			},
+		},
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"$.data.items[1].name", "b"},
+		{"data.items[0]['state']", "OK"},
+		{"$.data.items[*].name", "a,b"},
+		{"$.data.missing", ""},
+		{"$.data.items[5].name", ""},
+	}
+	for _, test := range tests {
+		values, err := evalJSONPath(data, test.path)
+		if err != nil {
+			t.Fatalf("%s: %v", test.path, err)
+		}
+		var got []string
+		for _, value := range values {
+			got = append(got, formatJSONValue(value))
+		}
+		if strings.Join(got, ",") != test.want {
+			t.Errorf("%s = %v, want %s", test.path, got, test.want)
+		}
+	}
+	if _, err := parseJSONPath("$.items[x"); err == nil {
+		t.Error("expected error for invalid path")
+	}
+}
+
+func TestCustomEndpoint(t *testing.T) {
+	fixture := copyFixture(loadFixture(t, "recorded"))
+	setEndpoint(&fixture, "system/licenses", fe2sim.Response{
+		Raw: `{"licenses":[{"name":"Pager","state":"VALID","used":5,"max":10},{"name":"AMweb","state":"EXPIRED","used":9},{"name":"Cloud"}]}`,
+	})
+	_, config := newTestServer(t, fixture)
+	warn, crit := 8.0, 10.0
+	config.CustomEndpoints = []CustomEndpoint{{
+		Name:    "license",
+		Path:    "system/licenses",
+		Items:   "$.licenses",
+		Service: "FE2 Lizenz: {name}",
+		State:   "state",
+		States:  map[string]int{"VALID": StateOK, "EXPIRED": StateCrit},
+		Summary: "{used} von {max} genutzt",
+		Metrics: []CustomMetric{{Name: "used", Value: "used", Warn: &warn, Crit: &crit}},
+	}}
+	if err := validateCustomEndpoints(config.CustomEndpoints); err != nil {
+		t.Fatal(err)
+	}
+
+	out, _ := runCheckmk(config, "license")
+	want := []string{
+		`0 "FE2 Lizenz: Pager" used=5;8;10 5 von 10 genutzt`,
+		`2 "FE2 Lizenz: AMweb" used=9;8;10 9 von - genutzt`,
+		`3 "FE2 Lizenz: Cloud" - - von - genutzt`,
+	}
+	for _, line := range want {
+		if !bytes.Contains(out, []byte(line+"\n")) {
+			t.Errorf("output does not contain %q:\n%s", line, out)
+		}
+	}
+	// Im Modus all laufen konfigurierte Endpunkte nach den eingebauten
+	if names := collectorNames(config, "all"); names[len(names)-1] != "license" {
+		t.Errorf("names = %v, want license last", names)
+	}
+}
+
+func TestValidateCustomEndpoints(t *testing.T) {
+	for _, endpoints := range [][]CustomEndpoint{
+		{{Name: "", Service: "x"}},
+		{{Name: "input", Service: "x"}},
+		{{Name: "a", Service: "x"}, {Name: "a", Service: "y"}},
+		{{Name: "a"}},
+		{{Name: "a", Service: "{name[}"}},
+		{{Name: "a", Service: "x", Metrics: []CustomMetric{{Name: "m"}}}},
+		{{Name: "a", Service: "x", Metrics: []CustomMetric{{Name: "m", Value: "count", Warn: new(float64)}}}},
+		{{Name: "a", Service: "x", States: map[string]int{"EXPIRED": 5}}},
+		{{Name: "a", Service: "x", States: map[string]int{"EXPIRED": -1}}},
+		{{Name: "serve", Service: "x"}},
+		{{Name: "capabilities", Service: "x"}},
+	} {
+		if err := validateCustomEndpoints(endpoints); err == nil {
+			t.Errorf("%+v: expected error", endpoints)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"checkmk_fe2/fe2sim"
+)
+
+// defaultDebugBodySize begrenzt den geloggten Body einer Antwort bei --debug-http
+const defaultDebugBodySize = 2048
+
+// debugTransport loggt jede Anfrage an FE2 mit Zeiten und gekürztem Body (--debug-http).
+// Das Token wird nie geloggt.
+type debugTransport struct {
+	next    http.RoundTripper
+	token   string
+	maxBody int
+	logger  *log.Logger
+}
+
+// newDebugTransport umhüllt next, ohne Transport wird http.DefaultTransport verwendet
+func newDebugTransport(next http.RoundTripper, token string, maxBody int) *debugTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if maxBody < 0 {
+		maxBody = 0
+	}
+	// Die Einträge werden mit Level debug geloggt. Ein eigener Logger mit Ziel und Format des
+	// Standard-Loggers schaltet das nur für diese Einträge ein, nicht für alle anderen Debug-Logs.
+	std := log.StandardLogger()
+	logger := &log.Logger{
+		Out:       std.Out,
+		Hooks:     std.Hooks,
+		Formatter: std.Formatter,
+		Level:     log.DebugLevel,
+		ExitFunc:  std.ExitFunc,
+	}
+	return &debugTransport{next: next, token: token, maxBody: maxBody, logger: logger}
+}
+
+// httpTimings enthält die Dauer der einzelnen Phasen einer Anfrage ab deren Start
+type httpTimings struct {
+	start                            time.Time
+	dnsStart, connectStart, tlsStart time.Time
+	dns, connect, tls, firstByte     time.Duration
+	reused                           bool
+}
+
+func (t *httpTimings) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { t.dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.dns = time.Since(t.dnsStart) },
+		ConnectStart:         func(string, string) { t.connectStart = time.Now() },
+		ConnectDone:          func(string, string, error) { t.connect = time.Since(t.connectStart) },
+		TLSHandshakeStart:    func() { t.tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.tls = time.Since(t.tlsStart) },
+		GotConn:              func(info httptrace.GotConnInfo) { t.reused = info.Reused },
+		GotFirstResponseByte: func() { t.firstByte = time.Since(t.start) },
+	}
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timings := &httpTimings{start: time.Now()}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), timings.trace()))
+	entry := t.logger.WithFields(log.Fields{
+		"method":   req.Method,
+		"url":      t.redact(req.URL.String()),
+		"headers":  fe2sim.RedactHeaders(req.Header),
+		"endpoint": endpointFromPath(req.URL.Path),
+	})
+
+	resp, err := t.next.RoundTrip(req)
+	entry = entry.WithFields(log.Fields{
+		"dns_ms":        milliseconds(timings.dns),
+		"connect_ms":    milliseconds(timings.connect),
+		"tls_ms":        milliseconds(timings.tls),
+		"first_byte_ms": milliseconds(timings.firstByte),
+		"total_ms":      milliseconds(time.Since(timings.start)),
+		"reused":        timings.reused,
+	})
+	if err != nil {
+		entry.WithError(err).Debug("HTTP request failed")
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		entry.WithError(err).Debug("HTTP response body could not be read")
+		return resp, nil
+	}
+	logged := string(body)
+	if len(body) > t.maxBody {
+		logged = fmt.Sprintf("%s... (%d bytes)", body[:t.maxBody], len(body))
+	}
+	entry.WithFields(log.Fields{
+		"status": resp.Status,
+		"size":   len(body),
+		"body":   t.redact(logged),
+	}).Debug("HTTP response")
+	return resp, nil
+}
+
+// redact entfernt das Token aus Texten, falls FE2 es z.B. in einer Fehlermeldung zurückgibt
+func (t *debugTransport) redact(s string) string {
+	if t.token == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, t.token, fe2sim.Redacted)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestDebugTransport(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	level := log.GetLevel()
+	defer func() {
+		log.SetOutput(io.Discard)
+		log.SetLevel(level)
+	}()
+
+	log.SetLevel(log.InfoLevel)
+	_, config := newTestServer(t, loadFixture(t, "recorded"))
+	config.Transport = newDebugTransport(nil, config.Token, 16)
+	// Nur die Einträge des Transports werden mit Level debug geloggt
+	if log.IsLevelEnabled(log.DebugLevel) {
+		t.Error("--debug-http enabled all debug logs")
+	}
+	var status Status
+	if err := apiGet(config, "status", &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.State == "" {
+		t.Errorf("response body was not passed on: %+v", status)
+	}
+
+	got := logs.String()
+	if strings.Contains(got, config.Token) {
+		t.Errorf("token was logged: %s", got)
+	}
+	for _, want := range []string{"method=GET", "status=\"200 OK\"", "connect_ms=", "first_byte_ms=", "bytes)", "Authorization:[<redacted>]"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log does not contain %s: %s", want, got)
+		}
+	}
+}
+
+func TestDebugTransportNegativeBody(t *testing.T) {
+	log.SetOutput(io.Discard)
+	_, config := newTestServer(t, loadFixture(t, "recorded"))
+	config.Transport = newDebugTransport(nil, config.Token, -1)
+	var status Status
+	if err := apiGet(config, "status", &status); err != nil {
+		t.Fatal(err)
+	}
+}
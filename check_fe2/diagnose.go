@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const proxyAuthHint = "Der Proxy verlangt eine Anmeldung, proxy.username und proxy.password eintragen"
+
+// diagnosis schreibt den Bericht von check_fe2 diagnose
+type diagnosis struct {
+	w      io.Writer
+	failed int
+}
+
+func (d *diagnosis) ok(step, detail string) {
+	fmt.Fprintf(d.w, "[OK]     %-22s %s\n", step, detail)
+}
+
+func (d *diagnosis) warn(step, detail, hint string) {
+	fmt.Fprintf(d.w, "[WARN]   %-22s %s\n", step, detail)
+	fmt.Fprintf(d.w, "         %-22s -> %s\n", "", hint)
+}
+
+func (d *diagnosis) fail(step, detail, hint string) {
+	d.failed++
+	fmt.Fprintf(d.w, "[FEHLER] %-22s %s\n", step, detail)
+	fmt.Fprintf(d.w, "         %-22s -> %s\n", "", hint)
+}
+
+// diagnose prüft Schritt für Schritt die Verbindung zu FE2 (check_fe2 diagnose)
+// und liefert 0, wenn alle Schritte erfolgreich waren
+func diagnose(config Config, w io.Writer) int {
+	d := &diagnosis{w: w}
+	fmt.Fprintf(w, "FE2 Diagnose für %s\n\n", config.ApiURL)
+
+	if !d.checkConfig(config) {
+		return d.summary()
+	}
+	proxyURL, ok := d.checkProxy(config)
+	if !ok {
+		return d.summary()
+	}
+	if proxyURL != nil {
+		// Über einen Proxy wird nur dieser direkt geprüft, die Verbindung zu FE2 über die Endpunkte
+		if !d.checkDNS(proxyURL.Hostname()) || !d.checkTCP(proxyAddress(proxyURL), config.Timeout, true) {
+			return d.summary()
+		}
+	} else {
+		address := net.JoinHostPort(config.Hostname, config.Port)
+		if !d.checkDNS(config.Hostname) || !d.checkTCP(address, config.Timeout, false) {
+			return d.summary()
+		}
+		if config.Protocol == "https" && !d.checkTLS(address, config.Hostname, config.Timeout) {
+			return d.summary()
+		}
+	}
+	d.checkEndpoints(config)
+	return d.summary()
+}
+
+// diagnoseConfigError schreibt den Bericht, wenn die Konfiguration nicht gelesen werden kann
+func diagnoseConfigError(err error, w io.Writer) int {
+	d := &diagnosis{w: w}
+	path := getConfigFilePath()
+	fmt.Fprintf(w, "FE2 Diagnose für %s\n\n", path)
+	hint := "Pfad prüfen bzw. über CHECK_FE2_CONFIG setzen oder mit check_fe2 config init anlegen"
+	if !errors.Is(err, fs.ErrNotExist) {
+		hint = "Syntax und Werte in " + path + " prüfen, check_fe2 config init --force schreibt eine neue Konfiguration"
+	}
+	d.fail("Konfiguration", err.Error(), hint)
+	return d.summary()
+}
+
+func (d *diagnosis) summary() int {
+	fmt.Fprintln(d.w)
+	if d.failed > 0 {
+		fmt.Fprintf(d.w, "%d Schritt(e) fehlgeschlagen\n", d.failed)
+		return StateCrit
+	}
+	fmt.Fprintln(d.w, "Alle Schritte erfolgreich")
+	return StateOK
+}
+
+func (d *diagnosis) checkConfig(config Config) bool {
+	valid := true
+	if config.Hostname == "" || config.Port == "" {
+		d.fail("Konfiguration", "hostname oder port fehlt", "hostname und port (Standard 83) in "+getConfigFilePath()+" eintragen")
+		valid = false
+	}
+	if config.Protocol != "http" && config.Protocol != "https" {
+		d.fail("Konfiguration", fmt.Sprintf("protocol %q ist ungültig", config.Protocol), "protocol: http oder protocol: https eintragen")
+		valid = false
+	}
+	if config.Token == "" {
+		d.fail("Konfiguration", "token ist leer", "Token aus dem Monitoring Plugin in FE2 als token eintragen")
+		valid = false
+	}
+	if valid {
+		d.ok("Konfiguration", fmt.Sprintf("%s://%s:%s, Token gesetzt", config.Protocol, config.Hostname, config.Port))
+	}
+	return valid
+}
+
+// checkProxy zeigt den Proxy für die Anfragen an FE2, nil bedeutet eine direkte Verbindung
+func (d *diagnosis) checkProxy(config Config) (*url.URL, bool) {
+	req, err := http.NewRequest("GET", config.ApiURL, nil)
+	var proxyURL *url.URL
+	if err == nil {
+		proxyURL, err = proxyFor(config.Proxy, req)
+	}
+	if err != nil {
+		d.fail("Proxy", err.Error(), "proxy.url in der Konfiguration bzw. HTTP_PROXY und HTTPS_PROXY prüfen")
+		return nil, false
+	}
+	if proxyURL == nil {
+		d.ok("Proxy", "keiner, direkte Verbindung")
+		return nil, true
+	}
+	d.ok("Proxy", proxyURL.Redacted())
+	return proxyURL, true
+}
+
+// proxyAddress liefert Host und Port des Proxys, ohne Port den Standardport des Schemas
+func proxyAddress(proxyURL *url.URL) string {
+	port := proxyURL.Port()
+	if port == "" {
+		port = "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), port)
+}
+
+func (d *diagnosis) checkDNS(hostname string) bool {
+	if ip := net.ParseIP(hostname); ip != nil {
+		d.ok("Namensauflösung", hostname+" ist eine IP-Adresse")
+		return true
+	}
+	start := time.Now()
+	addresses, err := net.DefaultResolver.LookupHost(context.Background(), hostname)
+	if err != nil {
+		d.fail("Namensauflösung", err.Error(), "Hostname prüfen, DNS-Server prüfen oder die IP-Adresse des FE2 Servers als hostname eintragen")
+		return false
+	}
+	d.ok("Namensauflösung", fmt.Sprintf("%s -> %s (%s)", hostname, strings.Join(addresses, ", "), formatLatency(time.Since(start))))
+	return true
+}
+
+func (d *diagnosis) checkTCP(address string, timeout time.Duration, proxy bool) bool {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		hint := "Netzwerkverbindung und Firewall zwischen checkmk Agent und FE2 prüfen"
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			hint = "Keine Antwort innerhalb von " + timeout.String() + ", vermutlich blockiert eine Firewall den Port"
+		} else if strings.Contains(err.Error(), "refused") && proxy {
+			hint = "Verbindung abgelehnt: Adresse und Port des Proxys prüfen"
+		} else if strings.Contains(err.Error(), "refused") {
+			hint = "Verbindung abgelehnt: läuft FE2 und ist der Port richtig (Standard 83)?"
+		}
+		d.fail("TCP-Verbindung", err.Error(), hint)
+		return false
+	}
+	conn.Close()
+	d.ok("TCP-Verbindung", fmt.Sprintf("%s erreichbar (%s)", address, formatLatency(time.Since(start))))
+	return true
+}
+
+func (d *diagnosis) checkTLS(address, hostname string, timeout time.Duration) bool {
+	start := time.Now()
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: hostname})
+	if err != nil {
+		hint := "Läuft FE2 auf diesem Port mit https? Sonst protocol: http eintragen"
+		var unknownAuthority x509.UnknownAuthorityError
+		var hostnameErr x509.HostnameError
+		var invalidErr x509.CertificateInvalidError
+		switch {
+		case errors.As(err, &unknownAuthority):
+			hint = "Das Zertifikat ist nicht vertrauenswürdig, die ausstellende CA im Zertifikatsspeicher des Systems hinterlegen"
+		case errors.As(err, &hostnameErr):
+			hint = "Das Zertifikat passt nicht zum hostname, einen im Zertifikat enthaltenen Namen als hostname eintragen"
+		case errors.As(err, &invalidErr):
+			hint = "Das Zertifikat ist ungültig oder abgelaufen und muss in FE2 erneuert werden"
+		}
+		d.fail("TLS-Handshake", err.Error(), hint)
+		return false
+	}
+	defer conn.Close()
+	state := conn.ConnectionState()
+	d.ok("TLS-Handshake", fmt.Sprintf("%s, %s (%s)", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite), formatLatency(time.Since(start))))
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		d.ok("Zertifikat", fmt.Sprintf("%s, ausgestellt von %s, Namen: %s", cert.Subject.CommonName, cert.Issuer.CommonName, joinOrDash(cert.DNSNames)))
+		remaining := time.Until(cert.NotAfter)
+		if remaining < 30*24*time.Hour {
+			d.warn("Zertifikat", "gültig bis "+cert.NotAfter.Format("2006-01-02"), "Das Zertifikat läuft in weniger als 30 Tagen ab und sollte erneuert werden")
+		} else {
+			d.ok("Zertifikat", "gültig bis "+cert.NotAfter.Format("2006-01-02"))
+		}
+	}
+	return true
+}
+
+// checkEndpoints fragt jeden Endpunkt mit Token ab und dekodiert die Antwort
+func (d *diagnosis) checkEndpoints(config Config) {
+	var inputs []InputService
+	targets := []struct {
+		Endpoint string
+		Target   interface{}
+	}{
+		{"input", &inputs},
+		{"amweb", &[]Amweb{}},
+		{"cloud", &[]CloudService{}},
+		{"status", &Status{}},
+		{"mqtt", &Mqtt{}},
+	}
+	for _, target := range targets {
+		d.checkEndpoint(config, target.Endpoint, target.Target)
+		// Der erste Eingang wird zusätzlich im Detail abgefragt
+		if target.Endpoint == "input" && len(inputs) > 0 {
+			d.checkEndpoint(config, "input/"+inputs[0].ID, &InputServiceDetail{})
+		}
+	}
+}
+
+func (d *diagnosis) checkEndpoint(config Config, endpoint string, target interface{}) {
+	step := "GET " + endpoint
+	start := time.Now()
+	err := apiGet(config, endpoint, target)
+	latency := formatLatency(time.Since(start))
+	if err == nil {
+		d.ok(step, "200 OK, JSON gültig ("+latency+")")
+		return
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		hint := "FE2 meldet einen Fehler, die Logs des FE2 Servers prüfen"
+		switch {
+		case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+			hint = "Das Token ist ungültig oder hat keine Berechtigung, das Token aus dem Monitoring Plugin in FE2 neu übernehmen"
+		case apiErr.StatusCode == http.StatusProxyAuthRequired:
+			hint = proxyAuthHint
+		case apiErr.StatusCode == http.StatusNotFound:
+			hint = "Der Endpunkt wird von dieser FE2 Version nicht angeboten oder der Pfad stimmt nicht (Port der Weboberfläche statt REST?)"
+		}
+		d.fail(step, apiErr.Status+" ("+latency+")", hint)
+		return
+	}
+	if strings.Contains(err.Error(), http.StatusText(http.StatusProxyAuthRequired)) {
+		d.fail(step, err.Error(), proxyAuthHint)
+		return
+	}
+	if strings.Contains(err.Error(), "proxyconnect") {
+		d.fail(step, err.Error(), "Der Proxy ist nicht erreichbar, proxy.url bzw. HTTP_PROXY und HTTPS_PROXY prüfen")
+		return
+	}
+	if strings.Contains(err.Error(), "error decoding JSON") {
+		d.fail(step, err.Error(), "Die Antwort ist kein gültiges JSON der Monitoring-Schnittstelle, evtl. antwortet ein anderer Dienst auf diesem Port")
+		return
+	}
+	d.fail(step, err.Error(), "Die Anfrage ist fehlgeschlagen, Timeout ("+config.Timeout.String()+") und Erreichbarkeit prüfen")
+}
+
+func formatLatency(d time.Duration) string {
+	return fmt.Sprintf("%.1f ms", milliseconds(d))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func diagnoseTestServer(t *testing.T, token string) (string, int) {
+	t.Helper()
+	_, config := newTestServer(t, loadFixture(t, "recorded"))
+	serverURL, err := url.Parse(config.ApiURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Protocol = serverURL.Scheme
+	config.Hostname = serverURL.Hostname()
+	config.Port = serverURL.Port()
+	config.Token = token
+
+	var out bytes.Buffer
+	exitCode := diagnose(config, &out)
+	return out.String(), exitCode
+}
+
+func TestDiagnose(t *testing.T) {
+	report, exitCode := diagnoseTestServer(t, "test-token")
+	if exitCode != StateOK {
+		t.Errorf("exit code = %d, want 0\n%s", exitCode, report)
+	}
+	for _, want := range []string{"TCP-Verbindung", "GET input/6567a1f0c2e4a1b0d8f3e001", "GET mqtt", "Alle Schritte erfolgreich"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report does not contain %q\n%s", want, report)
+		}
+	}
+}
+
+func TestDiagnoseInvalidToken(t *testing.T) {
+	report, exitCode := diagnoseTestServer(t, "wrong-token")
+	if exitCode != StateCrit {
+		t.Errorf("exit code = %d, want %d", exitCode, StateCrit)
+	}
+	if !strings.Contains(report, "[FEHLER] GET status") || !strings.Contains(report, "Token ist ungültig") {
+		t.Errorf("report does not explain the invalid token\n%s", report)
+	}
+}
+
+func TestDiagnoseConfigError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "check_fe2.yaml")
+	t.Setenv("CHECK_FE2_CONFIG", path)
+	_, err := tryLoadConfig()
+	if err == nil {
+		t.Fatal("missing config file was loaded")
+	}
+
+	var out bytes.Buffer
+	if exitCode := diagnoseConfigError(err, &out); exitCode != StateCrit {
+		t.Errorf("exit code = %d, want %d", exitCode, StateCrit)
+	}
+	report := out.String()
+	for _, want := range []string{"[FEHLER] Konfiguration", path, "CHECK_FE2_CONFIG", "check_fe2 config init"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report does not contain %q\n%s", want, report)
+		}
+	}
+}
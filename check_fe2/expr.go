@@ -0,0 +1,341 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Ausdrücke der Regeln (rules), z.B.
+//
+//	connectionState != "OK" && nbrOfWebSocketConnections == 0
+//	state == "ERROR" && message contains "Timeout"
+//
+// Bezeichner sind JSON-Pfade in den Daten eines Eintrags (Feldnamen wie in der Antwort von FE2),
+// @service ist der Name des Services und @state dessen Zustand (0-3).
+// Operatoren: == != < <= > >= contains matches && || ! und Klammern.
+
+// ruleEnv sind die Werte, gegen die ein Ausdruck ausgewertet wird
+type ruleEnv struct {
+	item    interface{}
+	service string
+	state   int
+}
+
+type exprNode interface {
+	eval(env ruleEnv) interface{}
+}
+
+type literalNode struct{ value interface{} }
+
+type fieldNode struct{ path string }
+
+type notNode struct{ operand exprNode }
+
+type logicalNode struct {
+	and         bool
+	left, right exprNode
+}
+
+type compareNode struct {
+	op          string
+	left, right exprNode
+	pattern     *regexp.Regexp
+}
+
+// parseExpr übersetzt einen Ausdruck, Fehler enthalten die Position im Ausdruck
+func parseExpr(source string) (exprNode, error) {
+	tokens, err := tokenizeExpr(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens, source: source}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("invalid expression %q: unexpected %q", source, p.tokens[p.pos].text)
+	}
+	return node, nil
+}
+
+type exprToken struct {
+	kind string // string, number, ident, op
+	text string
+}
+
+var exprOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"}
+
+func tokenizeExpr(source string) ([]exprToken, error) {
+	var tokens []exprToken
+	rest := source
+	for {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			return tokens, nil
+		}
+		first, size := utf8.DecodeRuneInString(rest)
+		switch c := rest[0]; {
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(rest[1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("invalid expression %q: unterminated string", source)
+			}
+			tokens = append(tokens, exprToken{"string", rest[1 : end+1]})
+			rest = rest[end+2:]
+			continue
+		case c >= '0' && c <= '9' || c == '-':
+			end := strings.IndexFunc(rest[1:], func(r rune) bool { return !unicode.IsDigit(r) && r != '.' }) + 1
+			if end == 0 {
+				end = len(rest)
+			}
+			tokens = append(tokens, exprToken{"number", rest[:end]})
+			rest = rest[end:]
+			continue
+		case c == '@' || c == '_' || unicode.IsLetter(first):
+			end := strings.IndexFunc(rest, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_@.[]*", r)
+			})
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid expression %q: unexpected %q", source, rest[:size])
+			}
+			tokens = append(tokens, exprToken{"ident", rest[:end]})
+			rest = rest[end:]
+			continue
+		}
+		matched := false
+		for _, op := range exprOperators {
+			if strings.HasPrefix(rest, op) {
+				tokens = append(tokens, exprToken{"op", op})
+				rest = rest[len(op):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("invalid expression %q: unexpected %q", source, rest[:size])
+		}
+	}
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+	source string
+}
+
+func (p *exprParser) peek(text string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].text == text && (p.tokens[p.pos].kind == "op" || p.tokens[p.pos].kind == "ident")
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.peek("||") {
+		p.pos++
+		var right exprNode
+		if right, err = p.parseAnd(); err == nil {
+			left = logicalNode{and: false, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	for err == nil && p.peek("&&") {
+		p.pos++
+		var right exprNode
+		if right, err = p.parseNot(); err == nil {
+			left = logicalNode{and: true, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if p.peek("!") {
+		p.pos++
+		operand, err := p.parseNot()
+		return notNode{operand}, err
+	}
+	return p.parseCompare()
+}
+
+func (p *exprParser) parseCompare() (exprNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">", "contains", "matches"} {
+		if !p.peek(op) {
+			continue
+		}
+		p.pos++
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		node := compareNode{op: op, left: left, right: right}
+		if op == "matches" {
+			literal, ok := right.(literalNode)
+			pattern, isString := literal.value.(string)
+			if !ok || !isString {
+				return nil, fmt.Errorf("invalid expression %q: matches needs a string", p.source)
+			}
+			if node.pattern, err = regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("invalid expression %q: %w", p.source, err)
+			}
+		}
+		return node, nil
+	}
+	return left, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("invalid expression %q: unexpected end", p.source)
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	switch token.kind {
+	case "string":
+		return literalNode{token.text}, nil
+	case "number":
+		value, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression %q: number %q", p.source, token.text)
+		}
+		return literalNode{value}, nil
+	case "ident":
+		switch token.text {
+		case "true", "false":
+			return literalNode{token.text == "true"}, nil
+		case "null":
+			return literalNode{nil}, nil
+		case "contains", "matches":
+			return nil, fmt.Errorf("invalid expression %q: unexpected %q", p.source, token.text)
+		}
+		if _, err := parseJSONPath(token.text); err != nil {
+			return nil, err
+		}
+		return fieldNode{token.text}, nil
+	}
+	if token.text == "(" {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek(")") {
+			return nil, fmt.Errorf("invalid expression %q: missing )", p.source)
+		}
+		p.pos++
+		return node, nil
+	}
+	return nil, fmt.Errorf("invalid expression %q: unexpected %q", p.source, token.text)
+}
+
+func (n literalNode) eval(env ruleEnv) interface{} {
+	return n.value
+}
+
+func (n fieldNode) eval(env ruleEnv) interface{} {
+	switch n.path {
+	case "@service":
+		return env.service
+	case "@state":
+		return float64(env.state)
+	}
+	value, _ := lookupJSONPath(env.item, n.path)
+	return value
+}
+
+func (n notNode) eval(env ruleEnv) interface{} {
+	return !truthy(n.operand.eval(env))
+}
+
+func (n logicalNode) eval(env ruleEnv) interface{} {
+	left := truthy(n.left.eval(env))
+	if n.and {
+		return left && truthy(n.right.eval(env))
+	}
+	return left || truthy(n.right.eval(env))
+}
+
+func (n compareNode) eval(env ruleEnv) interface{} {
+	left, right := n.left.eval(env), n.right.eval(env)
+	switch n.op {
+	case "==":
+		return equalValues(left, right)
+	case "!=":
+		return !equalValues(left, right)
+	case "contains":
+		if list, ok := left.([]interface{}); ok {
+			for _, element := range list {
+				if equalValues(element, right) {
+					return true
+				}
+			}
+			return false
+		}
+		text, ok1 := left.(string)
+		part, ok2 := right.(string)
+		return ok1 && ok2 && strings.Contains(text, part)
+	case "matches":
+		text, ok := left.(string)
+		return ok && n.pattern.MatchString(text)
+	}
+	// Größenvergleiche gelten nur für zwei Zahlen oder zwei Texte
+	if a, ok := left.(float64); ok {
+		if b, ok := right.(float64); ok {
+			return compareOrdered(n.op, a, b)
+		}
+	}
+	if a, ok := left.(string); ok {
+		if b, ok := right.(string); ok {
+			return compareOrdered(n.op, a, b)
+		}
+	}
+	return false
+}
+
+func compareOrdered[T float64 | string](op string, a, b T) bool {
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	}
+	return a >= b
+}
+
+// equalValues vergleicht zwei JSON-Werte, Listen und Objekte sind nie gleich
+func equalValues(a, b interface{}) bool {
+	switch a.(type) {
+	case nil, string, float64, bool:
+		return a == b
+	}
+	return false
+}
+
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case float64:
+		return v != 0
+	}
+	return true
+}
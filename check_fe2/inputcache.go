@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const inputCacheFile = "inputs.json"
+
+// InputDetailsConfig legt fest, für welche Eingänge /input/{id} abgefragt wird.
+// Bei großen Installationen spart das je Lauf eine Anfrage pro Eingang.
+type InputDetailsConfig struct {
+	// Fetch ist always (Standard) oder not_ok: dann werden nur Eingänge abgefragt, die laut Liste nicht OK sind
+	Fetch string `yaml:"fetch"`
+	// RefreshEvery fragt bei not_ok die Details gesunder Eingänge zusätzlich jeden N-ten Lauf ab, 0 nie
+	RefreshEvery int `yaml:"refresh_every"`
+}
+
+// inputCache speichert die letzte Meldung jedes Eingangs einer Instanz. Die Datei im cache_dir
+// enthält die Caches aller Instanzen, damit sich mehrere FE2 Server ein cache_dir teilen können.
+type inputCache struct {
+	Run    int                          `json:"run"`
+	Inputs map[string]cachedInputDetail `json:"inputs"`
+
+	instance string
+	refresh  bool
+}
+
+type cachedInputDetail struct {
+	Message string    `json:"message"`
+	Fetched time.Time `json:"fetched"`
+}
+
+// validateInputDetails prüft input_details beim Lesen der Konfiguration
+func validateInputDetails(config InputDetailsConfig) error {
+	switch config.Fetch {
+	case "", "always", "not_ok":
+	default:
+		return fmt.Errorf("input_details: invalid fetch %q, must be always or not_ok", config.Fetch)
+	}
+	if config.RefreshEvery < 0 {
+		return fmt.Errorf("input_details: refresh_every must not be negative")
+	}
+	return nil
+}
+
+// loadInputCache liefert nil, wenn die Details immer abgefragt werden
+func loadInputCache(config Config) *inputCache {
+	if config.InputDetails.Fetch != "not_ok" {
+		return nil
+	}
+	cache := &inputCache{instance: config.instance()}
+	caches, err := readInputCaches(config)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		config.logger().WithError(err).Warn("Error reading input cache")
+	}
+	if cached, ok := caches[cache.instance]; ok {
+		cache.Run, cache.Inputs = cached.Run, cached.Inputs
+	}
+	if cache.Inputs == nil {
+		cache.Inputs = make(map[string]cachedInputDetail)
+	}
+	cache.Run++
+	cache.refresh = config.InputDetails.RefreshEvery > 0 && cache.Run%config.InputDetails.RefreshEvery == 0
+	return cache
+}
+
+func readInputCaches(config Config) (map[string]*inputCache, error) {
+	caches := make(map[string]*inputCache)
+	if config.CacheDir == "" {
+		return caches, nil
+	}
+	data, err := os.ReadFile(filepath.Join(config.CacheDir, inputCacheFile))
+	if err != nil {
+		return caches, err
+	}
+	return caches, json.Unmarshal(data, &caches)
+}
+
+// lookup liefert einen gesunden Eingang aus der Liste mit der zwischengespeicherten Meldung.
+// Eingänge, die nicht OK sind oder noch keine Meldung haben, müssen abgefragt werden.
+func (c *inputCache) lookup(service InputService) (InputServiceDetail, bool) {
+	if c == nil || c.refresh || service.State != "OK" {
+		return InputServiceDetail{}, false
+	}
+	cached, ok := c.Inputs[service.ID]
+	if !ok {
+		return InputServiceDetail{}, false
+	}
+	return InputServiceDetail{ID: service.ID, Name: service.Name, Message: cached.Message, State: service.State}, true
+}
+
+// retain entfernt Eingänge, die FE2 nicht mehr liefert
+func (c *inputCache) retain(services []InputService) {
+	if c == nil {
+		return
+	}
+	current := make(map[string]bool, len(services))
+	for _, service := range services {
+		current[service.ID] = true
+	}
+	for id := range c.Inputs {
+		if !current[id] {
+			delete(c.Inputs, id)
+		}
+	}
+}
+
+func (c *inputCache) store(detail InputServiceDetail) {
+	if c == nil {
+		return
+	}
+	c.Inputs[detail.ID] = cachedInputDetail{Message: detail.Message, Fetched: time.Now()}
+}
+
+// save schreibt den Cache dieser Instanz in das cache_dir, andere Instanzen bleiben erhalten
+func (c *inputCache) save(config Config) {
+	if c == nil || config.CacheDir == "" {
+		return
+	}
+	caches, err := readInputCaches(config)
+	if err != nil {
+		caches = make(map[string]*inputCache)
+	}
+	caches[c.instance] = c
+	data, err := json.MarshalIndent(caches, "", "  ")
+	if err == nil {
+		err = writeCacheFile(config, inputCacheFile, data)
+	}
+	if err != nil {
+		config.logger().WithError(err).Warn("Error writing input cache")
+	}
+}
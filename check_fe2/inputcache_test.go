@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInputDetailsOnlyNotOK(t *testing.T) {
+	simulator, config := newTestServer(t, loadFixture(t, "recorded"))
+	want := inputLines(runCheckmk(config, "input"))
+	healthy, failing := "input/6567a1f0c2e4a1b0d8f3e001", "input/6567a1f0c2e4a1b0d8f3e002"
+	beforeHealthy, beforeFailing := simulator.Requests(healthy), simulator.Requests(failing)
+
+	config.CacheDir = t.TempDir()
+	config.InputDetails = InputDetailsConfig{Fetch: "not_ok", RefreshEvery: 3}
+	// Lauf 1 füllt den Cache, in Lauf 2 wird nur der Eingang mit ERROR abgefragt, Lauf 3 frischt alle auf
+	wantHealthy := []int{1, 1, 2}
+	wantFailing := []int{1, 2, 3}
+	for run := 0; run < 3; run++ {
+		got := inputLines(runCheckmk(config, "input"))
+		if got != want {
+			t.Errorf("run %d: output differs:\n%s\nwant:\n%s", run+1, got, want)
+		}
+		if got := simulator.Requests(healthy) - beforeHealthy; got != wantHealthy[run] {
+			t.Errorf("run %d: healthy input fetched %d times, want %d", run+1, got, wantHealthy[run])
+		}
+		if got := simulator.Requests(failing) - beforeFailing; got != wantFailing[run] {
+			t.Errorf("run %d: failing input fetched %d times, want %d", run+1, got, wantFailing[run])
+		}
+	}
+}
+
+func TestInputCachePerInstance(t *testing.T) {
+	dir := t.TempDir()
+	first := Config{ApiURL: "http://fe2-a:83/rest/monitoring/", CacheDir: dir, InputDetails: InputDetailsConfig{Fetch: "not_ok"}}
+	second := first
+	second.ApiURL = "http://fe2-b:83/rest/monitoring/"
+
+	cache := loadInputCache(first)
+	cache.store(InputServiceDetail{ID: "a1", Message: "OK"})
+	cache.save(first)
+	// Die zweite Instanz kennt a1 nicht und darf den Eintrag der ersten nicht entfernen
+	cache = loadInputCache(second)
+	cache.retain([]InputService{{ID: "b1"}})
+	cache.store(InputServiceDetail{ID: "b1", Message: "OK"})
+	cache.save(second)
+
+	cache = loadInputCache(first)
+	if _, ok := cache.lookup(InputService{ID: "a1", State: "OK"}); !ok || cache.Run != 2 {
+		t.Errorf("cache of the first instance was changed: run %d, inputs %v", cache.Run, cache.Inputs)
+	}
+	if cache := loadInputCache(second); cache.Run != 2 || len(cache.Inputs) != 1 {
+		t.Errorf("second instance: run %d, inputs %v", cache.Run, cache.Inputs)
+	}
+
+	for _, invalid := range []InputDetailsConfig{{Fetch: "sometimes"}, {Fetch: "not_ok", RefreshEvery: -1}} {
+		if err := validateInputDetails(invalid); err == nil {
+			t.Errorf("%+v: expected error", invalid)
+		}
+	}
+}
+
+// inputLines liefert nur die Services der Eingänge, die Anzahl der Anfragen unterscheidet sich
+func inputLines(out []byte, _ []EndpointResult) string {
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.Contains(line, "FE2 Input: ") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const binaryName = "check_fe2"
+
+// agentLocalDir liefert das local Verzeichnis des Linux Agents
+func agentLocalDir() string {
+	return filepath.Join(envOrDefault("MK_LIBDIR", "/usr/lib/check_mk_agent"), "local")
+}
+
+// install kopiert check_fe2 in das local Verzeichnis des Agents (check_fe2 install [--interval N]).
+// Mit --interval wird es in das Unterverzeichnis N abgelegt und vom Agent asynchron alle N Sekunden ausgeführt.
+func install(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("install", flag.ExitOnError)
+	interval := flags.Int("interval", 0, "run asynchronously every N seconds (interval subdirectory)")
+	localDir := flags.String("local-dir", agentLocalDir(), "local directory of the checkmk agent")
+	configFile := flags.String("config", "", "config file to place or merge into "+getConfigFilePath())
+	overwrite := flags.Bool("overwrite-config", false, "values from --config replace existing values")
+	skipVerify := flags.Bool("skip-verify", false, "do not run the installed plugin once")
+	flags.Parse(args)
+
+	if runtime.GOOS == "windows" {
+		fmt.Fprintln(out, `Unter Windows check_fe2.exe nach %ProgramData%\checkmk\agent\local kopieren`)
+		return 1
+	}
+	if *interval < 0 {
+		fmt.Fprintln(out, "--interval muss positiv sein")
+		return 1
+	}
+
+	targetDir := *localDir
+	if *interval > 0 {
+		targetDir = filepath.Join(*localDir, strconv.Itoa(*interval))
+	}
+	target := filepath.Join(targetDir, binaryName)
+
+	source, err := os.Executable()
+	if err != nil {
+		fmt.Fprintln(out, "Fehler beim Ermitteln des Programms:", err)
+		return 1
+	}
+	if err := copyExecutable(source, target); err != nil {
+		fmt.Fprintln(out, "Fehler beim Kopieren:", err)
+		return 1
+	}
+	fmt.Fprintln(out, "Installiert:", target)
+	// Installationen mit anderem Intervall werden entfernt, damit der Check nicht doppelt läuft
+	for _, old := range installedBinaries(*localDir) {
+		if old != target {
+			if err := os.Remove(old); err == nil {
+				fmt.Fprintln(out, "Entfernt:", old)
+			}
+		}
+	}
+
+	configPath := getConfigFilePath()
+	if *configFile != "" {
+		if err := mergeConfigFile(*configFile, configPath, *overwrite); err != nil {
+			fmt.Fprintln(out, "Fehler beim Übernehmen der Konfiguration:", err)
+			return 1
+		}
+		fmt.Fprintln(out, "Konfiguration:", configPath)
+	} else if _, err := os.Stat(configPath); err != nil {
+		fmt.Fprintf(out, "%s fehlt, mit check_fe2 config init erstellen\n", configPath)
+		return 1
+	}
+
+	if *skipVerify {
+		return 0
+	}
+	return verifyInstallation(target, out)
+}
+
+// uninstall entfernt check_fe2 aus dem local Verzeichnis, mit --purge auch Konfiguration, Overrides, Logs und Cache
+func uninstall(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("uninstall", flag.ExitOnError)
+	localDir := flags.String("local-dir", agentLocalDir(), "local directory of the checkmk agent")
+	purge := flags.Bool("purge", false, "also remove the config file, overrides, log files and cached state")
+	flags.Parse(args)
+
+	removed := 0
+	for _, path := range installedBinaries(*localDir) {
+		if err := os.Remove(path); err != nil {
+			fmt.Fprintln(out, "Fehler beim Entfernen:", err)
+			return 1
+		}
+		fmt.Fprintln(out, "Entfernt:", path)
+		removed++
+	}
+	if removed == 0 {
+		fmt.Fprintln(out, "check_fe2 ist in", *localDir, "nicht installiert")
+	}
+
+	if *purge {
+		config, err := readConfigFile(getConfigFilePath())
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintln(out, "Konfiguration nicht lesbar, es werden die Standardpfade entfernt:", err)
+		}
+		files, cacheDir := purgePaths(config)
+		for _, path := range files {
+			err := os.Remove(path)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				fmt.Fprintln(out, "Fehler beim Entfernen:", err)
+				return 1
+			}
+			fmt.Fprintln(out, "Entfernt:", path)
+		}
+		// Das cache_dir kann konfiguriert und mit anderen Programmen geteilt sein, es wird nur leer entfernt
+		if entries, err := os.ReadDir(cacheDir); err == nil && len(entries) > 0 {
+			fmt.Fprintln(out, "Nicht leer, bleibt erhalten:", cacheDir)
+		} else if err == nil {
+			if err := os.Remove(cacheDir); err != nil {
+				fmt.Fprintln(out, "Fehler beim Entfernen:", err)
+				return 1
+			}
+			fmt.Fprintln(out, "Entfernt:", cacheDir)
+		}
+	}
+	return 0
+}
+
+// purgePaths liefert die Dateien, die check_fe2 mit der Konfiguration config anlegt: Konfiguration,
+// Overrides, Logs mit rotierten Dateien und die Dateien im cache_dir, sowie das cache_dir selbst
+func purgePaths(config Config) ([]string, string) {
+	paths := []string{getConfigFilePath(), overridesPath(config)}
+	// Nur echte Dateien, ein Log nach /dev/stderr o.ä. bleibt unberührt
+	if info, err := os.Lstat(config.Log.File); err == nil && info.Mode().IsRegular() {
+		rotated, _ := filepath.Glob(config.Log.File + ".[0-9]*")
+		paths = append(append(paths, config.Log.File), rotated...)
+	}
+	cacheDir := config.CacheDir
+	if cacheDir == "" {
+		cacheDir = getCacheDir()
+	}
+	for _, name := range []string{capabilitiesFile, circuitFile, inputCacheFile, pluginStateFile, lockFile} {
+		paths = append(paths, filepath.Join(cacheDir, name))
+	}
+	return paths, cacheDir
+}
+
+// installedBinaries liefert alle Installationen im local Verzeichnis und dessen Intervall-Unterverzeichnissen
+func installedBinaries(localDir string) []string {
+	var paths []string
+	candidates, _ := filepath.Glob(filepath.Join(localDir, "*", binaryName))
+	for _, candidate := range append([]string{filepath.Join(localDir, binaryName)}, candidates...) {
+		if _, err := os.Stat(candidate); err == nil {
+			paths = append(paths, candidate)
+		}
+	}
+	return paths
+}
+
+// copyExecutable kopiert das Programm über eine temporäre Datei, damit ein laufender Agent keine halbe Datei ausführt
+func copyExecutable(source, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	tmp := target + ".tmp"
+	if err := os.WriteFile(tmp, data, 0755); err != nil {
+		return err
+	}
+	return os.Rename(tmp, target)
+}
+
+// mergeConfigFile übernimmt die Werte aus source in die Konfiguration unter target.
+// Vorhandene Werte bleiben erhalten, außer overwrite ist gesetzt.
+func mergeConfigFile(source, target string, overwrite bool) error {
+	var values yaml.MapSlice
+	if err := readYAMLFile(source, &values); err != nil {
+		return err
+	}
+	var merged yaml.MapSlice
+	if err := readYAMLFile(target, &merged); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for _, item := range values {
+		found := false
+		for i := range merged {
+			if merged[i].Key == item.Key {
+				found = true
+				if overwrite {
+					merged[i].Value = item.Value
+				}
+			}
+		}
+		if !found {
+			merged = append(merged, item)
+		}
+	}
+
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("merged config is invalid: %w", err)
+	}
+	if err := validateConfig(config); err != nil {
+		return fmt.Errorf("merged config is invalid: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(target, data, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(target, 0600)
+}
+
+func readYAMLFile(filename string, target interface{}) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, target)
+}
+
+var localCheckLine = regexp.MustCompile(`^([0-3P]) "[^"]+" \S+ `)
+
+// verifyInstallation führt das installierte Programm einmal aus und prüft die Ausgabe
+func verifyInstallation(target string, out io.Writer) int {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(target)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	start := time.Now()
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(out, "Testlauf fehlgeschlagen: %v\n%s", err, stderr.String())
+		return 1
+	}
+
+	services := 0
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		if !localCheckLine.MatchString(scanner.Text()) {
+			fmt.Fprintf(out, "Testlauf liefert eine ungültige Zeile: %q\n", scanner.Text())
+			return 1
+		}
+		services++
+	}
+	if services == 0 {
+		fmt.Fprintf(out, "Testlauf liefert keine Services\n%s", stderr.String())
+		return 1
+	}
+	fmt.Fprintf(out, "Testlauf erfolgreich: %d Services in %s\n", services, time.Since(start).Round(time.Millisecond))
+	if stderr.Len() > 0 {
+		log.Warn("Test run wrote to stderr: ", stderr.String())
+	}
+	return 0
+}
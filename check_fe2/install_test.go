@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// loadConfigFile liest die Konfiguration wie check_fe2 über CHECK_FE2_CONFIG
+func loadConfigFile(t *testing.T, path string) Config {
+	t.Helper()
+	t.Setenv("CHECK_FE2_CONFIG", path)
+	config, err := tryLoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func TestMergeConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.yaml")
+	target := filepath.Join(dir, "config.yaml")
+	os.WriteFile(source, []byte("hostname: fe2.example.org\nport: 83\nprotocol: http\ntoken: new-token\namweb_organisations: true\n"), 0644)
+	os.WriteFile(target, []byte("hostname: 127.0.0.1\ntoken: old-token\ntimeout: 5s\n"), 0644)
+
+	if err := mergeConfigFile(source, target, false); err != nil {
+		t.Fatal(err)
+	}
+	config := loadConfigFile(t, target)
+	// Vorhandene Werte bleiben erhalten, fehlende werden ergänzt
+	if config.Hostname != "127.0.0.1" || config.Token != "old-token" || config.Port != "83" || !config.AmwebOrganisations || config.Timeout.String() != "5s" {
+		t.Errorf("unexpected merged config %+v", config)
+	}
+
+	if err := mergeConfigFile(source, target, true); err != nil {
+		t.Fatal(err)
+	}
+	if config := loadConfigFile(t, target); config.Hostname != "fe2.example.org" || config.Token != "new-token" {
+		t.Errorf("values were not overwritten: %+v", config)
+	}
+}
+
+func TestInstallAndUninstall(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("install is only supported on Linux")
+	}
+	dir := t.TempDir()
+	localDir := filepath.Join(dir, "local")
+	t.Setenv("CHECK_FE2_CONFIG", filepath.Join(dir, "check_fe2.yaml"))
+	t.Setenv("MK_VARDIR", dir)
+	source := filepath.Join(dir, "source.yaml")
+	os.WriteFile(source, []byte("hostname: 127.0.0.1\nport: 83\nprotocol: http\ntoken: test-token\n"), 0644)
+
+	var out bytes.Buffer
+	if exitCode := install([]string{"--local-dir", localDir, "--config", source, "--skip-verify"}, &out); exitCode != 0 {
+		t.Fatalf("install failed\n%s", out.String())
+	}
+	// Ein Wechsel des Intervalls entfernt die vorherige Installation
+	if exitCode := install([]string{"--local-dir", localDir, "--interval", "300", "--skip-verify"}, &out); exitCode != 0 {
+		t.Fatalf("install with interval failed\n%s", out.String())
+	}
+	want := []string{filepath.Join(localDir, "300", binaryName)}
+	if got := installedBinaries(localDir); len(got) != 1 || got[0] != want[0] {
+		t.Errorf("installed = %v, want %v", got, want)
+	}
+
+	if exitCode := uninstall([]string{"--local-dir", localDir, "--purge"}, &out); exitCode != 0 {
+		t.Fatalf("uninstall failed\n%s", out.String())
+	}
+	if got := installedBinaries(localDir); len(got) != 0 {
+		t.Errorf("still installed: %v", got)
+	}
+	if _, err := os.Stat(getConfigFilePath()); !os.IsNotExist(err) {
+		t.Error("config was not removed by --purge")
+	}
+}
+
+func TestUninstallPurgeConfiguredPaths(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "etc", "check_fe2.yaml")
+	t.Setenv("CHECK_FE2_CONFIG", configPath)
+	t.Setenv("MK_VARDIR", filepath.Join(dir, "var"))
+	cacheDir := filepath.Join(dir, "cache")
+	logFile := filepath.Join(dir, "log", "check_fe2.log")
+	os.MkdirAll(filepath.Dir(configPath), 0700)
+	os.MkdirAll(filepath.Dir(logFile), 0700)
+	os.MkdirAll(cacheDir, 0700)
+	os.WriteFile(configPath, []byte("cache_dir: "+cacheDir+"\nlog:\n  file: "+logFile+"\n"), 0600)
+
+	owned := []string{
+		filepath.Join(dir, "etc", overridesFileName),
+		logFile, logFile + ".1",
+		filepath.Join(cacheDir, capabilitiesFile), filepath.Join(cacheDir, inputCacheFile),
+	}
+	for _, path := range owned {
+		os.WriteFile(path, []byte("x"), 0600)
+	}
+	// Fremde Dateien im konfigurierten cache_dir bleiben erhalten
+	foreign := filepath.Join(cacheDir, "other.json")
+	os.WriteFile(foreign, []byte("x"), 0600)
+
+	var out bytes.Buffer
+	if exitCode := uninstall([]string{"--local-dir", filepath.Join(dir, "local"), "--purge"}, &out); exitCode != 0 {
+		t.Fatalf("uninstall failed\n%s", out.String())
+	}
+	for _, path := range append(owned, configPath) {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed by --purge", path)
+		}
+	}
+	if _, err := os.Stat(foreign); err != nil {
+		t.Errorf("foreign file in cache_dir was removed: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathStep ist ein Schritt eines JSON-Pfads: ein Feld, ein Index oder [*] für alle Elemente
+type pathStep struct {
+	field string
+	index int
+	all   bool
+}
+
+// parseJSONPath zerlegt einen einfachen JSON-Pfad wie $.data.items[0].name oder items[*].state.
+// Der Pfad beginnt optional mit $, ein leerer Pfad bezeichnet den Wert selbst.
+func parseJSONPath(path string) ([]pathStep, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	var steps []pathStep
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			continue
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSON path %q: missing ]", path)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			if inner == "*" {
+				steps = append(steps, pathStep{all: true})
+				continue
+			}
+			if quoted, err := strconv.Unquote(strings.ReplaceAll(inner, "'", `"`)); err == nil {
+				steps = append(steps, pathStep{field: quoted})
+				continue
+			}
+			index, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSON path %q: index %q", path, inner)
+			}
+			steps = append(steps, pathStep{index: index})
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			steps = append(steps, pathStep{field: rest[:end]})
+			rest = rest[end:]
+		}
+	}
+	return steps, nil
+}
+
+// evalJSONPath liefert alle Werte, auf die der Pfad in einem dekodierten JSON-Wert zeigt
+func evalJSONPath(value interface{}, path string) ([]interface{}, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	values := []interface{}{value}
+	for _, step := range steps {
+		var next []interface{}
+		for _, value := range values {
+			switch v := value.(type) {
+			case map[string]interface{}:
+				if field, ok := v[step.field]; ok && !step.all && step.field != "" {
+					next = append(next, field)
+				}
+			case []interface{}:
+				if step.all {
+					next = append(next, v...)
+				} else if step.field == "" && step.index >= 0 && step.index < len(v) {
+					next = append(next, v[step.index])
+				}
+			}
+		}
+		values = next
+	}
+	return values, nil
+}
+
+// lookupJSONPath liefert den ersten Wert eines Pfads, ok ist false, wenn der Pfad nicht existiert
+func lookupJSONPath(value interface{}, path string) (interface{}, bool) {
+	values, err := evalJSONPath(value, path)
+	if err != nil || len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
+
+// formatJSONValue gibt einen dekodierten JSON-Wert für die Ausgabe in einem Service aus
+func formatJSONValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "-"
+	case string:
+		return v
+	case float64:
+		return formatValue(v)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultLogMaxSize    = 10 // MB
+	defaultLogMaxBackups = 3
+)
+
+// LogConfig steuert das Logging. Logs gehen nie auf stdout, damit die Ausgabe für den Agent gültig bleibt.
+type LogConfig struct {
+	// Level ist eines von debug, info, warn, error, Standard ist info
+	Level string `yaml:"level"`
+	// Format ist text oder json, Standard ist text
+	Format string `yaml:"format"`
+	// File schreibt die Logs in eine Datei statt auf stderr
+	File string `yaml:"file"`
+	// MaxSize ist die Größe in MB, ab der die Datei rotiert wird, Standard sind 10 MB
+	MaxSize int `yaml:"max_size_mb"`
+	// MaxBackups ist die Anzahl der aufbewahrten rotierten Dateien, Standard sind 3
+	MaxBackups int `yaml:"max_backups"`
+}
+
+// setupLogging richtet Level, Format und Ziel der Logs nach der Konfiguration ein
+func setupLogging(config LogConfig) error {
+	level := log.InfoLevel
+	if config.Level != "" {
+		var err error
+		if level, err = log.ParseLevel(config.Level); err != nil {
+			return err
+		}
+	}
+
+	var formatter log.Formatter
+	switch config.Format {
+	case "", "text":
+		formatter = &log.TextFormatter{DisableColors: true, FullTimestamp: true}
+	case "json":
+		formatter = &log.JSONFormatter{}
+	default:
+		return fmt.Errorf("unknown log format %q", config.Format)
+	}
+
+	var output io.Writer = os.Stderr
+	if config.File != "" {
+		if isStdout(config.File) {
+			return fmt.Errorf("log file %s would write to stdout", config.File)
+		}
+		file, err := newRotatingFile(config.File, config.MaxSize, config.MaxBackups)
+		if err != nil {
+			return err
+		}
+		output = file
+	}
+
+	log.SetLevel(level)
+	log.SetFormatter(formatter)
+	log.SetOutput(output)
+	return nil
+}
+
+// isStdout prüft, ob path auf stdout zeigt, z.B. /dev/stdout oder die Datei, in die stdout umgeleitet ist
+func isStdout(path string) bool {
+	if path == "-" || path == "/dev/stdout" || path == "/dev/fd/1" {
+		return true
+	}
+	stdout, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && os.SameFile(info, stdout)
+}
+
+// logger liefert einen Logger mit der FE2 Instanz als Feld
+func (config Config) logger() *log.Entry {
+	return log.WithField("instance", config.instance())
+}
+
+// instance identifiziert den FE2 Server in Logs, z.B. fe2.example.org:83
+func (config Config) instance() string {
+	if u, err := url.Parse(config.ApiURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return config.Hostname
+}
+
+// endpointFields liefert die Felder eines Endpunkts, für input/<id> zusätzlich die ID des Eingangs
+func endpointFields(endpoint string) log.Fields {
+	fields := log.Fields{"endpoint": endpoint}
+	if id := strings.TrimPrefix(endpoint, "input/"); id != endpoint {
+		fields["endpoint"] = "input"
+		fields["input"] = id
+	}
+	return fields
+}
+
+// rotatingFile ist eine Logdatei, die ab einer Größe nach <Datei>.1, <Datei>.2 usw. verschoben wird
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func newRotatingFile(path string, maxSizeMB, maxBackups int) (*rotatingFile, error) {
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultLogMaxSize
+	}
+	if maxBackups <= 0 {
+		maxBackups = defaultLogMaxBackups
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("error creating log directory: %w", err)
+	}
+	r := &rotatingFile{path: path, maxSize: int64(maxSizeMB) * 1024 * 1024, maxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("error opening log file: %w", err)
+	}
+	r.file, r.size = file, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate verschiebt die vorhandenen Dateien um eine Nummer und öffnet eine neue Datei
+func (r *rotatingFile) rotate() error {
+	r.file.Close()
+	os.Remove(fmt.Sprintf("%s.%d", r.path, r.maxBackups))
+	for i := r.maxBackups - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error rotating log file: %w", err)
+	}
+	return r.open()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "check_fe2.log")
+	file, err := newRotatingFile(path, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := strings.Repeat("x", 400*1024) + "\n"
+	for i := 0; i < 8; i++ {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if info.Size() > 1024*1024 {
+			t.Errorf("%s has %d bytes, want at most 1 MB", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("more than 2 backups kept: %v", err)
+	}
+}
+
+func TestSetupLogging(t *testing.T) {
+	defer func() {
+		log.SetOutput(io.Discard)
+		log.SetFormatter(&log.TextFormatter{})
+		log.SetLevel(log.InfoLevel)
+	}()
+
+	for _, config := range []LogConfig{{File: "/dev/stdout"}, {File: "-"}, {Format: "xml"}, {Level: "verbose"}} {
+		if err := setupLogging(config); err == nil {
+			t.Errorf("%+v: expected error", config)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "check_fe2.log")
+	if err := setupLogging(LogConfig{Level: "debug", Format: "json", File: path}); err != nil {
+		t.Fatal(err)
+	}
+	config := Config{ApiURL: "http://fe2.example.org:83/rest/monitoring/"}
+	config.logger().WithFields(endpointFields("input/42")).Debug("FE2 request")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"instance":"fe2.example.org:83"`, `"endpoint":"input"`, `"input":"42"`, `"level":"debug"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("log %q does not contain %s", data, want)
+		}
+	}
+}
+
+func TestCheckFailureLoggedOnce(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(io.Discard)
+
+	fixture := copyFixture(loadFixture(t, "recorded"))
+	delete(fixture.Endpoints, "input/6567a1f0c2e4a1b0d8f3e002")
+	_, config := newTestServer(t, fixture)
+	runChecks(config, "input")
+
+	if got := strings.Count(logs.String(), "level=info"); got != 1 {
+		t.Errorf("got %d info entries, want 1:\n%s", got, logs.String())
+	}
+	if !strings.Contains(logs.String(), "input=6567a1f0c2e4a1b0d8f3e002") {
+		t.Errorf("log does not contain the input: %s", logs.String())
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"checkmk_fe2/fe2sim"
+)
+
+// Aufruf: check_fe2 [all|input|amweb|cloud|status|mqtt] [--output checkmk|nagios|json] [--record DIR|--replay DIR] [--debug-http]
+// oder:   check_fe2 serve [--listen :9712]
+// oder:   check_fe2 explain [endpoint]
+// oder:   check_fe2 ack list|add|remove
+// oder:   check_fe2 diagnose
+// oder:   check_fe2 config init
+// oder:   check_fe2 install [--interval N] | uninstall [--purge]
+func main() {
+	// Logs gehen immer auf stderr oder in eine Datei, stdout enthält nur die Ausgabe für den Agent
+	log.SetOutput(os.Stderr)
+	command := "all"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		serve(configure(loadConfig()), args)
+	case "explain":
+		os.Exit(explain(configure(loadConfig()), args, os.Stdout))
+	case "ack":
+		os.Exit(ackCommand(configure(loadConfig()), args, os.Stdout))
+	case "diagnose":
+		// Auch eine fehlerhafte Konfiguration wird im Bericht erklärt
+		config, err := tryLoadConfig()
+		if err != nil {
+			os.Exit(diagnoseConfigError(err, os.Stdout))
+		}
+		os.Exit(diagnose(configure(config), os.Stdout))
+	case "config":
+		os.Exit(configCommand(args, os.Stdin, os.Stdout))
+	case "install":
+		os.Exit(install(args, os.Stdout))
+	case "uninstall":
+		os.Exit(uninstall(args, os.Stdout))
+	default:
+		os.Exit(check(command, args))
+	}
+}
+
+// check führt die Checks für einen oder alle Endpunkte aus und liefert den Exit-Code
+func check(command string, args []string) int {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	output := flags.String("output", "checkmk", "output format: checkmk, nagios or json")
+	record := flags.String("record", "", "save every FE2 response to this directory")
+	replay := flags.String("replay", "", "answer requests from a directory created with --record")
+	debugHTTP := flags.Bool("debug-http", false, "log every HTTP request and response to FE2 (token redacted)")
+	debugBody := flags.Int("debug-http-body", defaultDebugBodySize, "maximum number of response body bytes logged with --debug-http")
+	flags.Parse(args)
+	if *debugBody < 0 {
+		log.Errorf("Invalid --debug-http-body %d, must not be negative", *debugBody)
+		return StateUnknown
+	}
+	// Mit --replay würden die Aufnahmen erneut in das Verzeichnis von --record geschrieben
+	if *record != "" && *replay != "" {
+		log.Error("--record and --replay cannot be used together")
+		return StateUnknown
+	}
+
+	// Konfiguration aus YAML-Datei lesen, für --replay ist sie optional.
+	// Ist sie nicht lesbar, meldet der Service "FE2 Plugin" den Fehler statt eines Abbruchs.
+	plugin := newPluginMonitor(getConfigFilePath())
+	var config Config
+	if _, err := os.Stat(getConfigFilePath()); *replay == "" || err == nil {
+		var err error
+		if config, err = tryLoadConfig(); err != nil {
+			log.WithError(err).Error("Error reading config file")
+			return writeResults(*output, os.Stdout, time.Now(), []EndpointResult{{Endpoint: "plugin", Results: []Result{plugin.configFailure(err)}}})
+		}
+		config = configure(config)
+	} else {
+		config = Config{ApiURL: "http://fe2-replay" + fe2sim.BasePath}
+	}
+	if *replay != "" {
+		config = isolateReplay(config)
+	}
+	if len(collectorNames(config, command)) == 0 {
+		log.Errorf("Unknown command %q", command)
+		return StateUnknown
+	}
+	// Ab hier darf das Programm nicht mit log.Fatal enden, sonst bleibt die Sperre bestehen
+	unlock := plugin.lock(config)
+	defer unlock()
+	switch {
+	case *replay != "":
+		transport, err := newReplayTransport(*replay)
+		if err != nil {
+			log.WithError(err).Error("Error loading recordings")
+			return StateUnknown
+		}
+		config.Transport = transport
+	case *record != "":
+		if err := os.MkdirAll(*record, 0700); err != nil {
+			log.WithError(err).Error("Error creating record directory")
+			return StateUnknown
+		}
+		config.Transport = &recordingTransport{dir: *record, next: config.Transport}
+	}
+	if *debugHTTP {
+		config.Transport = newDebugTransport(config.Transport, config.Token, *debugBody)
+	}
+
+	start := time.Now()
+	endpoints := runChecks(config, command)
+	endpoints = append(endpoints, EndpointResult{Endpoint: "plugin", Results: []Result{plugin.result(config)}})
+	return writeResults(*output, os.Stdout, start, endpoints)
+}
+
+// writeResults gibt die Ergebnisse im gewählten Format aus und liefert den Exit-Code
+func writeResults(output string, w io.Writer, start time.Time, endpoints []EndpointResult) int {
+	switch output {
+	case "checkmk":
+		return writeCheckmk(w, endpoints)
+	case "nagios":
+		return writeNagios(w, endpoints)
+	case "json":
+		return writeJSON(w, start, endpoints)
+	}
+	log.Errorf("Unknown output format %q", output)
+	return StateUnknown
+}
+
+// configure richtet nach dem Lesen der Konfiguration das Logging und den HTTP-Transport mit Proxy ein
+func configure(config Config) Config {
+	if err := setupLogging(config.Log); err != nil {
+		log.WithError(err).Error("Error setting up logging")
+	}
+	config.Transport = newTransport(config)
+	return config
+}
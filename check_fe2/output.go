@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// EndpointResult enthält die Services eines Endpunkts sowie einen eventuellen Fehler beim Abruf
+type EndpointResult struct {
+	Endpoint string
+	Data     interface{}
+	Results  []Result
+	Duration time.Duration
+	Err      error
+}
+
+// runChecks führt die Collectors für command (all oder ein Endpunkt) in der Reihenfolge der Registrierung aus
+func runChecks(config Config, command string) []EndpointResult {
+	names := collectorNames(config, command)
+	capabilities := loadCapabilities(config)
+	if config.SchemaCheck {
+		config.schema = newSchemaReport()
+	}
+	config.requests = &requestLog{}
+	overrides, err := loadOverrides(overridesPath(config))
+	if err != nil {
+		config.logger().WithError(err).Warn("Error reading overrides")
+	}
+	if config.CircuitBreaker.Failures > 0 {
+		config.breaker = loadCircuitBreaker(config)
+	}
+	// Im Modus all zeigt der Service "FE2 API Capabilities" die nicht unterstützten Endpunkte,
+	// bei einzelnen Endpunkten erhält jeder nicht unterstützte einen eigenen Service
+	all := command == "all"
+	var endpoints, unsupported []EndpointResult
+	for _, collector := range configCollectors(config) {
+		name := collector.Name()
+		if !containsString(names, name) {
+			continue
+		}
+		if capabilities.skip(name) {
+			if !all {
+				unsupported = append(unsupported, unsupportedEndpoint(name))
+			}
+			continue
+		}
+		start := time.Now()
+		data, results, err := runCollector(config, collector)
+		results = applyRules(config, name, results)
+		results = applyOverrides(overrides, name, results)
+		endpoints = append(endpoints, EndpointResult{Endpoint: name, Data: data, Results: results, Duration: time.Since(start), Err: err})
+	}
+	// Ein 404 bedeutet nur dann "nicht unterstützt", wenn ein anderer Endpunkt geantwortet hat.
+	// Antworten alle mit 404, sind z.B. Port oder Pfad falsch und nichts wird zwischengespeichert.
+	reachable := false
+	for _, endpoint := range endpoints {
+		reachable = reachable || endpoint.Err == nil
+	}
+	checked := endpoints[:0]
+	for _, endpoint := range endpoints {
+		if notFound(endpoint.Endpoint, endpoint.Err) && !reachable {
+			capabilities.unconfirmed = append(capabilities.unconfirmed, endpoint.Endpoint)
+		} else if capabilities.update(endpoint.Endpoint, endpoint.Err) {
+			// Von dieser FE2 Version nicht unterstützte Endpunkte werden im Modus all stillschweigend übersprungen
+			if !all {
+				unsupported = append(unsupported, unsupportedEndpoint(endpoint.Endpoint))
+			}
+			continue
+		}
+		if endpoint.Err != nil {
+			logCheckFailure(config, endpoint.Endpoint, endpoint.Err)
+		}
+		checked = append(checked, endpoint)
+	}
+	endpoints = checked
+	capabilities.save(config)
+	if config.breaker != nil {
+		config.breaker.save(config)
+	}
+
+	markAuthFailures(endpoints)
+	markCircuitOpen(endpoints)
+	markNotFound(endpoints)
+	apiResult := evaluateAPI(config.API, endpoints, config.requests)
+	authResult := evaluateAuthentication(endpoints)
+	endpoints = append(endpoints, unsupported...)
+	endpoints = append(endpoints, EndpointResult{Endpoint: "authentication", Results: []Result{authResult}})
+	endpoints = append(endpoints, EndpointResult{Endpoint: "api", Data: config.requests.requests, Results: []Result{apiResult}})
+	if config.breaker != nil {
+		endpoints = append(endpoints, EndpointResult{Endpoint: "circuit", Results: []Result{config.breaker.result()}})
+	}
+	if config.schema != nil {
+		endpoints = append(endpoints, EndpointResult{Endpoint: "schema", Results: []Result{config.schema.result()}})
+	}
+	if all {
+		endpoints = append(endpoints, EndpointResult{Endpoint: "capabilities", Data: capabilities, Results: []Result{capabilities.result()}})
+	}
+	return endpoints
+}
+
+// logCheckFailure loggt den Fehler eines Endpunkts einmal mit Level info. Fehler von FE2 sind keine
+// internen Fehler des Plugins und werden über die Services gemeldet.
+func logCheckFailure(config Config, endpoint string, err error) {
+	// Bei input/<id> enthält der Fehler den genauen Endpunkt mit der ID des Eingangs
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Endpoint != "" {
+		endpoint = apiErr.Endpoint
+	}
+	config.logger().WithFields(endpointFields(endpoint)).WithError(err).Info("Check failed")
+}
+
+// writeCheckmk gibt die Services im Format der checkmk Local Checks aus.
+// Fehler werden beim Abruf geloggt, der Exit-Code ist immer 0.
+func writeCheckmk(w io.Writer, endpoints []EndpointResult) int {
+	for _, endpoint := range endpoints {
+		for _, result := range endpoint.Results {
+			fmt.Fprintln(w, result.CheckmkLine())
+		}
+	}
+	return 0
+}
+
+var nagiosStateNames = map[int]string{
+	StateOK:      "OK",
+	StateWarn:    "WARNING",
+	StateCrit:    "CRITICAL",
+	StateUnknown: "UNKNOWN",
+}
+
+// writeNagios gibt die Services im Format eines Nagios/Icinga Plugins aus:
+// eine Statuszeile mit Performancedaten, danach eine Zeile je Service.
+// Der Rückgabewert ist der schlechteste Status und dient als Exit-Code.
+func writeNagios(w io.Writer, endpoints []EndpointResult) int {
+	worst := StateOK
+	var total int
+	var problems, details, perfdata []string
+	for _, endpoint := range endpoints {
+		// Fehler mit eigenem Service werden über diesen gemeldet, sonst stünden sie doppelt in der Ausgabe
+		if endpoint.Err != nil && len(endpoint.Results) == 0 {
+			worst = worstState(worst, StateUnknown)
+			problems = append(problems, endpoint.Endpoint+": "+endpoint.Err.Error())
+			details = append(details, fmt.Sprintf("[UNKNOWN] %s: %s", endpoint.Endpoint, endpoint.Err))
+		}
+		for _, result := range endpoint.Results {
+			state := result.EffectiveState()
+			worst = worstState(worst, state)
+			total++
+			if state != StateOK {
+				problems = append(problems, result.Name)
+			}
+			details = append(details, fmt.Sprintf("[%s] %s - %s", nagiosStateNames[state], result.Name, result.Summary))
+			for _, metric := range result.Metrics {
+				metric.Name = nagiosLabel(result.Name + " " + metric.Name)
+				perfdata = append(perfdata, metric.String())
+			}
+		}
+	}
+	if total == 0 && len(problems) == 0 {
+		worst = StateUnknown
+		problems = append(problems, "no services found")
+	}
+
+	status := fmt.Sprintf("FE2 %s - %d services", nagiosStateNames[worst], total)
+	if len(problems) > 0 {
+		status += ", problems: " + strings.Join(problems, ", ")
+	}
+	if len(perfdata) > 0 {
+		status += " | " + strings.Join(perfdata, " ")
+	}
+	fmt.Fprintln(w, status)
+	for _, detail := range details {
+		fmt.Fprintln(w, detail)
+	}
+	return worst
+}
+
+// nagiosLabel maskiert ein Performancedaten-Label, das Leerzeichen oder Sonderzeichen enthalten kann
+func nagiosLabel(label string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(label, "=", "_"), "'", "''") + "'"
+}
+
+// jsonDocument ist das Dokument, das mit --output json ausgegeben wird
+type jsonDocument struct {
+	Time       time.Time      `json:"time"`
+	DurationMs float64        `json:"durationMs"`
+	Endpoints  []jsonEndpoint `json:"endpoints"`
+}
+
+type jsonEndpoint struct {
+	Endpoint   string        `json:"endpoint"`
+	DurationMs float64       `json:"durationMs"`
+	Error      string        `json:"error,omitempty"`
+	Data       interface{}   `json:"data"`
+	Services   []jsonService `json:"services"`
+}
+
+type jsonService struct {
+	Result
+	StateName      string `json:"stateName"`
+	EffectiveState int    `json:"effectiveState"`
+}
+
+// writeJSON gibt die dekodierten Daten, die abgeleiteten Services, Laufzeiten und Fehler
+// aller Endpunkte als ein JSON-Dokument aus
+func writeJSON(w io.Writer, start time.Time, endpoints []EndpointResult) int {
+	document := jsonDocument{Time: start, Endpoints: []jsonEndpoint{}}
+	for _, endpoint := range endpoints {
+		entry := jsonEndpoint{
+			Endpoint:   endpoint.Endpoint,
+			DurationMs: milliseconds(endpoint.Duration),
+			Data:       endpoint.Data,
+			Services:   []jsonService{},
+		}
+		if endpoint.Err != nil {
+			entry.Error = endpoint.Err.Error()
+		}
+		for _, result := range endpoint.Results {
+			entry.Services = append(entry.Services, jsonService{Result: result, StateName: stateNames[result.State], EffectiveState: result.EffectiveState()})
+		}
+		document.Endpoints = append(document.Endpoints, entry)
+	}
+	document.DurationMs = milliseconds(time.Since(start))
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		log.WithError(err).Error("Error encoding JSON")
+		return StateUnknown
+	}
+	return 0
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
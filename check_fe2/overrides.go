@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const overridesFileName = "check_fe2_overrides.yaml"
+
+// Override markiert einen Eintrag als erwartet offline oder bestätigt (check_fe2 ack).
+// Solange er gilt, wird der Service OK mit dem Kommentar ausgegeben.
+type Override struct {
+	// Type ist der Endpunkt, z.B. input, amweb oder cloud
+	Type string `yaml:"type"`
+	// Name ist der Name oder die ID des Eintrags, z.B. des Eingangs oder AMweb Geräts
+	Name string `yaml:"name"`
+	// ExpectedDown markiert einen Eintrag, der absichtlich offline ist, sonst ist es eine Bestätigung
+	ExpectedDown bool   `yaml:"expected_down,omitempty"`
+	Comment      string `yaml:"comment"`
+	// Expires ist das Ablaufdatum (2006-01-02 oder 2006-01-02 15:04), ohne Angabe gilt der Eintrag unbefristet
+	Expires string `yaml:"expires,omitempty"`
+	Created string `yaml:"created,omitempty"`
+}
+
+type overridesFile struct {
+	Overrides []Override `yaml:"overrides"`
+}
+
+// overridesPath liefert den Pfad der Overrides, Standard ist check_fe2_overrides.yaml neben der Konfiguration.
+// Mit --replay werden keine Overrides gelesen, der Pfad ist dann leer.
+func overridesPath(config Config) string {
+	if config.isolated {
+		return ""
+	}
+	if config.OverridesFile != "" {
+		return config.OverridesFile
+	}
+	return filepath.Join(filepath.Dir(getConfigFilePath()), overridesFileName)
+}
+
+func loadOverrides(path string) ([]Override, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file overridesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", path, err)
+	}
+	return file.Overrides, nil
+}
+
+func saveOverrides(path string, overrides []Override) error {
+	data, err := yaml.Marshal(overridesFile{Overrides: overrides})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	header := "# Bestätigungen und erwartete Ausfälle, bearbeitet mit check_fe2 ack\n"
+	return os.WriteFile(path, append([]byte(header), data...), 0644)
+}
+
+// expiry liefert das Ende der Gültigkeit, ein Datum gilt bis zum Ende des Tages
+func (o Override) expiry() (time.Time, bool, error) {
+	if o.Expires == "" {
+		return time.Time{}, false, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", o.Expires, time.Local); err == nil {
+		return t, true, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", o.Expires, time.Local)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid expiry %q, use YYYY-MM-DD or YYYY-MM-DD HH:MM", o.Expires)
+	}
+	return t.AddDate(0, 0, 1), true, nil
+}
+
+func (o Override) active(now time.Time) bool {
+	expires, ok, err := o.expiry()
+	return err == nil && (!ok || now.Before(expires))
+}
+
+// note liefert den Hinweis, der an die Ausgabe des Services angehängt wird
+func (o Override) note() string {
+	kind := "Bestätigt"
+	if o.ExpectedDown {
+		kind = "Erwartet offline"
+	}
+	note := kind + ": " + o.Comment
+	if o.Expires != "" {
+		note += ", bis " + o.Expires
+	}
+	return note
+}
+
+// itemKeys liefert Name und ID eines Eintrags, mit denen ein Override übereinstimmen kann
+func itemKeys(item interface{}) []string {
+	var fields map[string]interface{}
+	if data, err := json.Marshal(item); err == nil {
+		json.Unmarshal(data, &fields)
+	}
+	var keys []string
+	for _, field := range []string{"name", "id", "identifier", "service"} {
+		if value, ok := fields[field].(string); ok && value != "" {
+			keys = append(keys, value)
+		}
+	}
+	return keys
+}
+
+// applyOverrides setzt Services mit einem gültigen Override auf OK und ergänzt den Kommentar
+func applyOverrides(overrides []Override, endpoint string, results []Result) []Result {
+	now := time.Now()
+	for i, result := range results {
+		if result.item == nil {
+			continue
+		}
+		keys := itemKeys(result.item)
+		for _, override := range overrides {
+			if override.Type != endpoint || !containsString(keys, override.Name) || !override.active(now) {
+				continue
+			}
+			results[i].State = StateOK
+			results[i].Reason = "Override: " + override.note()
+			results[i].Summary = strings.TrimSpace(result.Summary + " (" + override.note() + ")")
+			break
+		}
+	}
+	return results
+}
+
+// ackCommand verarbeitet check_fe2 ack list|add|remove
+func ackCommand(config Config, args []string, out io.Writer) int {
+	usage := "Usage: check_fe2 ack list | add --type TYPE --name NAME --comment TEXT [--expires YYYY-MM-DD] [--expected-down] | remove --type TYPE --name NAME"
+	if len(args) == 0 {
+		fmt.Fprintln(out, usage)
+		return 1
+	}
+	path := overridesPath(config)
+	overrides, err := loadOverrides(path)
+	if err != nil {
+		fmt.Fprintln(out, "Fehler:", err)
+		return 1
+	}
+
+	flags := flag.NewFlagSet("ack "+args[0], flag.ContinueOnError)
+	flags.SetOutput(out)
+	itemType := flags.String("type", "", "endpoint of the item, e.g. input, amweb or cloud")
+	name := flags.String("name", "", "name or ID of the item")
+	comment := flags.String("comment", "", "reason, shown in the service output")
+	expires := flags.String("expires", "", "expiry date YYYY-MM-DD or YYYY-MM-DD HH:MM")
+	expectedDown := flags.Bool("expected-down", false, "the item is intentionally offline, no expiry date needed")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 1
+	}
+
+	switch args[0] {
+	case "list":
+		if len(overrides) == 0 {
+			fmt.Fprintf(out, "Keine Einträge in %s\n", path)
+		}
+		now := time.Now()
+		for _, override := range overrides {
+			status := ""
+			if !override.active(now) {
+				status = " [abgelaufen]"
+			}
+			fmt.Fprintf(out, "%s %q: %s%s\n", override.Type, override.Name, override.note(), status)
+		}
+		return 0
+	case "add":
+		override := Override{Type: *itemType, Name: *name, ExpectedDown: *expectedDown, Comment: *comment, Expires: *expires, Created: time.Now().Format("2006-01-02 15:04")}
+		if err := validateOverride(config, override); err != nil {
+			fmt.Fprintln(out, "Fehler:", err)
+			return 1
+		}
+		// Ein vorhandener Eintrag für dasselbe Element wird ersetzt
+		overrides = removeOverride(overrides, override.Type, override.Name)
+		overrides = append(overrides, override)
+	case "remove":
+		remaining := removeOverride(overrides, *itemType, *name)
+		if len(remaining) == len(overrides) {
+			fmt.Fprintf(out, "Kein Eintrag für %s %q gefunden\n", *itemType, *name)
+			return 1
+		}
+		overrides = remaining
+	default:
+		fmt.Fprintln(out, usage)
+		return 1
+	}
+
+	if err := saveOverrides(path, overrides); err != nil {
+		fmt.Fprintln(out, "Fehler beim Schreiben:", err)
+		return 1
+	}
+	fmt.Fprintf(out, "%s gespeichert (%d Einträge)\n", path, len(overrides))
+	return 0
+}
+
+func validateOverride(config Config, override Override) error {
+	if len(collectorNames(config, override.Type)) == 0 || override.Type == "all" {
+		return fmt.Errorf("unknown type %q, use an endpoint like input, amweb or cloud", override.Type)
+	}
+	if override.Name == "" || override.Comment == "" {
+		return fmt.Errorf("--name and --comment are required")
+	}
+	if override.Expires == "" && !override.ExpectedDown {
+		return fmt.Errorf("--expires is required for an acknowledgement")
+	}
+	expires, ok, err := override.expiry()
+	if err != nil {
+		return err
+	}
+	if ok && !expires.After(time.Now()) {
+		return fmt.Errorf("expiry %s is in the past", override.Expires)
+	}
+	return nil
+}
+
+func removeOverride(overrides []Override, itemType, name string) []Override {
+	var remaining []Override
+	for _, override := range overrides {
+		if override.Type != itemType || override.Name != name {
+			remaining = append(remaining, override)
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAckCommand(t *testing.T) {
+	config := Config{OverridesFile: filepath.Join(t.TempDir(), overridesFileName)}
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	var out bytes.Buffer
+	for _, args := range [][]string{
+		{"add", "--type", "input", "--name", "E-Mail Leitstelle", "--comment", "IMAP Umstellung", "--expires", tomorrow},
+		{"add", "--type", "amweb", "--name", "AM-0815", "--comment", "Backup Gateway", "--expected-down"},
+		{"add", "--type", "cloud", "--name", "GEOCODING", "--comment", "Test", "--expires", tomorrow},
+		{"remove", "--type", "cloud", "--name", "GEOCODING"},
+	} {
+		if code := ackCommand(config, args, &out); code != 0 {
+			t.Fatalf("%v: exit code %d\n%s", args, code, out.String())
+		}
+	}
+	for _, args := range [][]string{
+		{"add", "--type", "pager", "--name", "x", "--comment", "y", "--expires", tomorrow},
+		{"add", "--type", "input", "--name", "x", "--comment", "y"},
+		{"add", "--type", "input", "--name", "x", "--comment", "y", "--expires", "2020-01-01"},
+		{"remove", "--type", "cloud", "--name", "GEOCODING"},
+	} {
+		if code := ackCommand(config, args, &out); code == 0 {
+			t.Errorf("%v: expected failure", args)
+		}
+	}
+
+	out.Reset()
+	ackCommand(config, []string{"list"}, &out)
+	want := "input \"E-Mail Leitstelle\": Bestätigt: IMAP Umstellung, bis " + tomorrow + "\namweb \"AM-0815\": Erwartet offline: Backup Gateway\n"
+	if out.String() != want {
+		t.Errorf("list = %q, want %q", out.String(), want)
+	}
+
+	_, server := newTestServer(t, loadFixture(t, "recorded"))
+	config.ApiURL, config.Token, config.Timeout = server.ApiURL, server.Token, server.Timeout
+	got, _ := runCheckmk(config, "all")
+	for _, line := range []string{
+		`0 "FE2 Input: E-Mail Leitstelle" - Verbindung zum IMAP Server fehlgeschlagen: Timeout (Bestätigt: IMAP Umstellung, bis ` + tomorrow + `)`,
+		`0 "AmWeb: AMweb Gerätehaus" connection=0 Organisation: FF Musterdorf ConnectionType: POLLING (Erwartet offline: Backup Gateway)`,
+		`1 "FE2 Cloud: GEOCODING"`,
+	} {
+		if !strings.Contains(string(got), line) {
+			t.Errorf("output does not contain %q:\n%s", line, got)
+		}
+	}
+}
+
+func TestOverrideExpired(t *testing.T) {
+	overrides := []Override{{Type: "cloud", Name: "GEOCODING", Comment: "alt", Expires: "2020-01-01"}}
+	results := applyOverrides(overrides, "cloud", evaluateCloud([]CloudService{{Name: "GEOCODING", State: "ERROR"}}))
+	if results[0].State != StateWarn {
+		t.Errorf("expired override was applied: %+v", results[0])
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// version wird beim Build gesetzt: go build -ldflags "-X main.version=1.2.0" ./check_fe2
+var version = ""
+
+const (
+	pluginStateFile = "plugin.json"
+	lockFile        = "check_fe2.lock"
+)
+
+// PluginLevels sind die Schwellwerte des Services "FE2 Plugin"
+type PluginLevels struct {
+	// RuntimeWarn und RuntimeCrit gelten für die Laufzeit des Plugins, Standard 30s/55s
+	RuntimeWarn time.Duration `yaml:"runtime_warn"`
+	RuntimeCrit time.Duration `yaml:"runtime_crit"`
+	// CacheAgeWarn und CacheAgeCrit gelten für das Alter des Caches, Standard 1h/24h
+	CacheAgeWarn time.Duration `yaml:"cache_age_warn"`
+	CacheAgeCrit time.Duration `yaml:"cache_age_crit"`
+	// LockWarn und LockCrit gelten, wenn ein vorheriger Lauf die Sperre noch hält, Standard 5m/15m
+	LockWarn time.Duration `yaml:"lock_warn"`
+	LockCrit time.Duration `yaml:"lock_crit"`
+}
+
+func (l PluginLevels) withDefaults() PluginLevels {
+	if l.RuntimeWarn == 0 {
+		l.RuntimeWarn = 30 * time.Second
+	}
+	if l.RuntimeCrit == 0 {
+		l.RuntimeCrit = 55 * time.Second
+	}
+	if l.CacheAgeWarn == 0 {
+		l.CacheAgeWarn = time.Hour
+	}
+	if l.CacheAgeCrit == 0 {
+		l.CacheAgeCrit = 24 * time.Hour
+	}
+	if l.LockWarn == 0 {
+		l.LockWarn = 5 * time.Minute
+	}
+	if l.LockCrit == 0 {
+		l.LockCrit = 15 * time.Minute
+	}
+	return l
+}
+
+// pluginVersion liefert die beim Build gesetzte Version oder die Version aus den Build-Informationen
+func pluginVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// errorHook merkt sich die Warnungen und Fehler, die das Plugin während eines Laufs loggt
+type errorHook struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (h *errorHook) Levels() []log.Level {
+	return []log.Level{log.PanicLevel, log.FatalLevel, log.ErrorLevel, log.WarnLevel}
+}
+
+func (h *errorHook) Fire(entry *log.Entry) error {
+	message := entry.Message
+	if err, ok := entry.Data[log.ErrorKey]; ok {
+		message += ": " + fmt.Sprint(err)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.messages = append(h.messages, message)
+	return nil
+}
+
+func (h *errorHook) last() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.messages) == 0 {
+		return ""
+	}
+	return h.messages[len(h.messages)-1]
+}
+
+// pluginMonitor sammelt die Daten für den Service "FE2 Plugin"
+type pluginMonitor struct {
+	start      time.Time
+	configPath string
+	hook       *errorHook
+	// lockAge ist das Alter der Sperre eines vorherigen, noch laufenden Laufs
+	lockAge time.Duration
+}
+
+// pluginState wird im cache_dir gespeichert, damit der letzte Fehler auch in späteren Läufen sichtbar bleibt
+type pluginState struct {
+	LastError     string    `json:"lastError"`
+	LastErrorTime time.Time `json:"lastErrorTime"`
+}
+
+func newPluginMonitor(configPath string) *pluginMonitor {
+	hook := &errorHook{}
+	log.AddHook(hook)
+	return &pluginMonitor{start: time.Now(), configPath: configPath, hook: hook}
+}
+
+// lock legt die Sperrdatei im cache_dir an. Hält ein anderer Lauf die Sperre noch, wird deren Alter
+// gemeldet und die Sperre nicht verändert. Eine Sperre älter als lock_crit stammt von einem
+// abgebrochenen Lauf und wird nach der Meldung übernommen.
+func (p *pluginMonitor) lock(config Config) func() {
+	if config.CacheDir == "" {
+		return func() {}
+	}
+	path := filepath.Join(config.CacheDir, lockFile)
+	pid := strconv.Itoa(os.Getpid())
+	file, err := createLockFile(path)
+	if errors.Is(err, os.ErrExist) {
+		if info, err := os.Stat(path); err == nil {
+			p.lockAge = time.Since(info.ModTime())
+		}
+		if p.lockAge < config.Plugin.withDefaults().LockCrit {
+			return func() {}
+		}
+		config.logger().WithField("age", formatAge(p.lockAge)).Info("Taking over stale lock file")
+		os.Remove(path)
+		file, err = createLockFile(path)
+	}
+	if err == nil {
+		_, err = file.WriteString(pid)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		config.logger().WithError(err).Warn("Error creating lock file")
+		return func() {}
+	}
+	return func() {
+		// Nur die eigene Sperre entfernen
+		if data, err := os.ReadFile(path); err == nil && string(data) == pid {
+			os.Remove(path)
+		}
+	}
+}
+
+// createLockFile legt die Sperrdatei an und schlägt fehl, wenn sie bereits existiert
+func createLockFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+}
+
+// configFailure liefert den Service "FE2 Plugin", wenn die Konfiguration nicht gelesen werden kann
+func (p *pluginMonitor) configFailure(err error) Result {
+	return Result{
+		State:   StateCrit,
+		Name:    "FE2 Plugin",
+		Reason:  "Konfiguration nicht lesbar",
+		Summary: fmt.Sprintf("Version %s, Konfiguration %s kann nicht gelesen werden: %v", pluginVersion(), p.configPath, err),
+	}
+}
+
+// result liefert den Service "FE2 Plugin" am Ende eines Laufs
+func (p *pluginMonitor) result(config Config) Result {
+	levels := config.Plugin.withDefaults()
+	runtime := time.Since(p.start)
+	metrics := []Metric{{
+		Name: "runtime", Value: runtime.Round(time.Millisecond).Seconds(),
+		Levels: true, Warn: levels.RuntimeWarn.Seconds(), Crit: levels.RuntimeCrit.Seconds(),
+	}}
+	details := []string{"Version " + pluginVersion()}
+	var problems []string
+
+	if info, err := os.Stat(p.configPath); err == nil {
+		age := time.Since(info.ModTime())
+		metrics = append(metrics, Metric{Name: "config_age", Value: age.Round(time.Second).Seconds()})
+		details = append(details, fmt.Sprintf("Konfiguration %s (geändert vor %s)", p.configPath, formatAge(age)))
+	} else {
+		details = append(details, "Konfiguration "+p.configPath)
+	}
+
+	if config.CacheDir != "" {
+		// Der Cache wird in jedem Lauf geschrieben, ein hohes Alter bedeutet, dass das Schreiben fehlschlägt
+		if info, err := os.Stat(filepath.Join(config.CacheDir, capabilitiesFile)); err == nil {
+			age := time.Since(info.ModTime())
+			metrics = append(metrics, Metric{
+				Name: "cache_age", Value: age.Round(time.Second).Seconds(),
+				Levels: true, Warn: levels.CacheAgeWarn.Seconds(), Crit: levels.CacheAgeCrit.Seconds(),
+			})
+			details = append(details, "Cache aktualisiert vor "+formatAge(age))
+		} else {
+			problems = append(problems, "Cache "+config.CacheDir+" wurde nicht geschrieben")
+		}
+	}
+
+	state := Result{State: StateDynamic, Metrics: metrics}.EffectiveState()
+	if p.lockAge > 0 {
+		metrics = append(metrics, Metric{Name: "lock_age", Value: p.lockAge.Round(time.Second).Seconds(), Levels: true, Warn: levels.LockWarn.Seconds(), Crit: levels.LockCrit.Seconds()})
+		if p.lockAge >= levels.LockCrit {
+			state = worstState(state, StateCrit)
+		} else if p.lockAge >= levels.LockWarn {
+			state = worstState(state, StateWarn)
+		}
+		problems = append(problems, "Sperre eines anderen Laufs seit "+formatAge(p.lockAge))
+	}
+
+	lastError := p.hook.last()
+	if lastError != "" {
+		state = worstState(state, StateWarn)
+		problems = append(problems, "Fehler: "+lastError)
+	}
+	if len(problems) > 0 {
+		state = worstState(state, StateWarn)
+	}
+	if previous := p.saveState(config, lastError); lastError == "" && previous.LastError != "" {
+		details = append(details, fmt.Sprintf("letzter Fehler vor %s: %s", formatAge(time.Since(previous.LastErrorTime)), previous.LastError))
+	}
+
+	return Result{
+		State:   state,
+		Name:    "FE2 Plugin",
+		Metrics: metrics,
+		Reason:  "Laufzeit, Cache-Alter, Sperre und interne Fehler",
+		Summary: strings.Join(append(problems, details...), ", "),
+	}
+}
+
+// saveState speichert den letzten Fehler und liefert den bisher gespeicherten Zustand
+func (p *pluginMonitor) saveState(config Config, lastError string) pluginState {
+	var state pluginState
+	if config.CacheDir == "" {
+		return state
+	}
+	if data, err := os.ReadFile(filepath.Join(config.CacheDir, pluginStateFile)); err == nil {
+		json.Unmarshal(data, &state)
+	}
+	if lastError == "" {
+		return state
+	}
+	previous := state
+	state = pluginState{LastError: lastError, LastErrorTime: time.Now()}
+	if data, err := json.Marshal(state); err == nil {
+		writeCacheFile(config, pluginStateFile, data)
+	}
+	return previous
+}
+
+// formatAge gibt eine Dauer gerundet und gut lesbar aus
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return d.Round(time.Second).String()
+	case d < time.Hour:
+		return d.Round(time.Minute).String()
+	}
+	return d.Round(time.Hour).String()
+}
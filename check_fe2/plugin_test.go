@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestPluginResult(t *testing.T) {
+	_, config := newTestServer(t, loadFixture(t, "recorded"))
+	config.CacheDir = t.TempDir()
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("hostname: fe2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	plugin := &pluginMonitor{start: time.Now(), configPath: configPath, hook: &errorHook{}}
+	runChecks(config, "all")
+
+	result := plugin.result(config)
+	if result.EffectiveState() != StateOK {
+		t.Errorf("state = %d, want %d (%s)", result.EffectiveState(), StateOK, result.Summary)
+	}
+	for _, name := range []string{"runtime", "config_age", "cache_age"} {
+		if !hasMetric(result, name) {
+			t.Errorf("metric %s missing in %q", name, result.CheckmkLine())
+		}
+	}
+
+	// Ein interner Fehler macht den Service WARN und bleibt im nächsten Lauf sichtbar
+	plugin.hook.Fire(log.WithError(errors.New("disk full")))
+	result = plugin.result(config)
+	if result.State != StateWarn || !strings.Contains(result.Summary, "disk full") {
+		t.Errorf("got %d %q, want WARN with last error", result.State, result.Summary)
+	}
+	next := &pluginMonitor{start: time.Now(), configPath: configPath, hook: &errorHook{}}
+	result = next.result(config)
+	if result.State != StateOK || !strings.Contains(result.Summary, "letzter Fehler") {
+		t.Errorf("got %d %q, want OK with previous error", result.State, result.Summary)
+	}
+}
+
+func TestPluginLock(t *testing.T) {
+	config := Config{CacheDir: t.TempDir()}
+	path := filepath.Join(config.CacheDir, lockFile)
+	if err := os.WriteFile(path, []byte("1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-20 * time.Minute)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	plugin := &pluginMonitor{start: time.Now(), configPath: "config.yaml", hook: &errorHook{}}
+	unlock := plugin.lock(config)
+	result := plugin.result(config)
+	if result.State != StateCrit {
+		t.Errorf("state = %d, want %d (%s)", result.State, StateCrit, result.Summary)
+	}
+	unlock()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("lock file was not removed: %v", err)
+	}
+
+	// Die Sperre eines laufenden Laufs wird weder überschrieben noch entfernt
+	if err := os.WriteFile(path, []byte("1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	plugin = &pluginMonitor{start: time.Now(), configPath: "config.yaml", hook: &errorHook{}}
+	unlock = plugin.lock(config)
+	if result := plugin.result(config); result.State == StateCrit || !strings.Contains(result.Summary, "Sperre eines anderen Laufs") {
+		t.Errorf("got %d %q, want the lock of another run below lock_crit", result.State, result.Summary)
+	}
+	unlock()
+	if data, err := os.ReadFile(path); err != nil || string(data) != "1" {
+		t.Errorf("lock of another run was changed: %q, %v", data, err)
+	}
+}
+
+func hasMetric(result Result, name string) bool {
+	for _, metric := range result.Metrics {
+		if metric.Name == name {
+			return true
+		}
+	}
+	return false
+}
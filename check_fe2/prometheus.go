@@ -0,0 +1,148 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// exporter stellt die Daten der Monitoring-Schnittstelle im Prometheus-Format bereit.
+// Die Antworten werden für cacheTTL zwischengespeichert, damit häufige Scrapes FE2 nicht belasten.
+type exporter struct {
+	config   Config
+	cacheTTL time.Duration
+
+	mu       sync.Mutex
+	snapshot *Snapshot
+}
+
+// serve startet den Prometheus Exporter (check_fe2 serve --listen :9712)
+func serve(config Config, args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := flags.String("listen", ":9712", "address to listen on")
+	cacheTTL := flags.Duration("cache-ttl", 30*time.Second, "how long FE2 responses are cached")
+	flags.Parse(args)
+
+	e := &exporter{config: config, cacheTTL: *cacheTTL}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+	config.logger().WithField("listen", *listen).Info("Starting Prometheus exporter")
+	if err := http.ListenAndServe(*listen, mux); err != nil {
+		log.WithError(err).Fatal("Error running Prometheus exporter")
+	}
+}
+
+// current liefert den zwischengespeicherten Snapshot oder ruft FE2 erneut ab.
+// Gleichzeitige Scrapes warten auf denselben Abruf.
+func (e *exporter) current() Snapshot {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.snapshot == nil || time.Since(e.snapshot.Time) > e.cacheTTL {
+		snapshot := collectSnapshot(e.config)
+		e.snapshot = &snapshot
+	}
+	return *e.snapshot
+}
+
+func (e *exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w, e.current())
+}
+
+// writeMetrics schreibt den Snapshot im Prometheus Text-Format
+func writeMetrics(w io.Writer, s Snapshot) {
+	m := &metricWriter{w: w}
+
+	m.header("fe2_up", "Whether the last request to the FE2 monitoring endpoint succeeded.")
+	for _, endpoint := range s.Endpoints {
+		up := 1.0
+		if endpoint.Err != nil {
+			up = 0
+		}
+		m.sample("fe2_up", up, "endpoint", endpoint.Name)
+	}
+	m.header("fe2_scrape_duration_seconds", "Duration of the request to the FE2 monitoring endpoint.")
+	for _, endpoint := range s.Endpoints {
+		m.sample("fe2_scrape_duration_seconds", endpoint.Duration.Seconds(), "endpoint", endpoint.Name)
+	}
+
+	m.header("fe2_input_state", "Checkmk state of the FE2 input (0 = OK, 1 = WARN).")
+	for _, input := range s.Inputs {
+		m.sample("fe2_input_state", float64(okState(input.State)), "id", input.ID, "name", input.Name, "state", input.State)
+	}
+
+	m.header("fe2_amweb_connection_state", "Checkmk state of the AMweb connection (0 = OK, 1 = WARN).")
+	for _, amweb := range s.Amwebs {
+		m.sample("fe2_amweb_connection_state", float64(okState(amweb.ConnectionState)), "identifier", amweb.Id, "name", amweb.Name, "organisation", amweb.Organisation, "connection_type", amweb.ConnectionType, "connection_state", amweb.ConnectionState)
+	}
+	m.header("fe2_amweb_websocket_connections", "Number of websocket connections of the AMweb device.")
+	for _, amweb := range s.Amwebs {
+		m.sample("fe2_amweb_websocket_connections", float64(amweb.ConnectionsCount), "identifier", amweb.Id, "name", amweb.Name, "organisation", amweb.Organisation, "connection_type", amweb.ConnectionType)
+	}
+
+	m.header("fe2_cloud_service_state", "Checkmk state of the FE2 cloud service (0 = OK, 1 = WARN).")
+	for _, service := range s.Cloud {
+		m.sample("fe2_cloud_service_state", float64(okState(service.State)), "service", service.Name, "state", service.State)
+	}
+
+	if endpointOK(s, "status") {
+		m.header("fe2_status_state", "Checkmk state of the FE2 self status (0 = OK, 1 = WARN).")
+		m.sample("fe2_status_state", float64(okState(s.Status.State)), "state", s.Status.State)
+		m.header("fe2_logged_errors", "Number of errors logged by FE2.")
+		m.sample("fe2_logged_errors", float64(s.Status.NbrOfLoggedErrors))
+		redundancy := s.Status.RedundancyState
+		m.header("fe2_redundancy_state", "Checkmk state of the FE2 redundancy (0 = OK, 1 = WARN).")
+		m.sample("fe2_redundancy_state", float64(okState(redundancy.State)), "state", redundancy.State, "current", redundancy.Current, "configured", redundancy.Configured)
+	}
+
+	if endpointOK(s, "mqtt") {
+		m.header("fe2_mqtt_state", "Checkmk state of the MQTT connection (0 = OK, 1 = WARN, 3 = UNKNOWN/not used).")
+		m.sample("fe2_mqtt_state", float64(mqttState(s.Mqtt.Defaultbroker)), "broker", "defaultBroker", "state", s.Mqtt.Defaultbroker)
+		m.sample("fe2_mqtt_state", float64(mqttState(s.Mqtt.Kubernetes)), "broker", "kubernetes", "state", s.Mqtt.Kubernetes)
+	}
+}
+
+func endpointOK(s Snapshot, name string) bool {
+	for _, endpoint := range s.Endpoints {
+		if endpoint.Name == name {
+			return endpoint.Err == nil
+		}
+	}
+	return false
+}
+
+// metricWriter schreibt Metriken im Prometheus Text-Format
+type metricWriter struct {
+	w io.Writer
+}
+
+func (m *metricWriter) header(name, help string) {
+	fmt.Fprintf(m.w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+}
+
+// sample schreibt einen Messwert, labels werden als Schlüssel/Wert-Paare übergeben
+func (m *metricWriter) sample(name string, value float64, labels ...string) {
+	var pairs []string
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", labels[i], escapeLabel(labels[i+1])))
+	}
+	sort.Strings(pairs)
+	if len(pairs) > 0 {
+		fmt.Fprintf(m.w, "%s{%s} %g\n", name, strings.Join(pairs, ","), value)
+	} else {
+		fmt.Fprintf(m.w, "%s %g\n", name, value)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
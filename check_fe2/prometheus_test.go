@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"checkmk_fe2/fe2sim"
+)
+
+// scrapeDurations maskiert die Dauer der Abrufe, damit das Golden File stabil bleibt
+var scrapeDurations = regexp.MustCompile(`(?m)^(fe2_scrape_duration_seconds\{[^}]*\}) \S+$`)
+
+func TestGoldenPrometheus(t *testing.T) {
+	_, config := newTestServer(t, loadFixture(t, "recorded"))
+	var out bytes.Buffer
+	writeMetrics(&out, collectSnapshot(config))
+	assertGolden(t, "prometheus", scrapeDurations.ReplaceAll(out.Bytes(), []byte("${1} <t>")))
+}
+
+func TestGoldenPrometheusErrors(t *testing.T) {
+	fixture := copyFixture(loadFixture(t, "recorded"))
+	delete(fixture.Endpoints, "mqtt")
+	setEndpoint(&fixture, "status", fe2sim.Response{Status: http.StatusInternalServerError})
+	_, config := newTestServer(t, fixture)
+	var out bytes.Buffer
+	writeMetrics(&out, collectSnapshot(config))
+	// Ohne Antwort fehlen die Metriken für status und mqtt, fe2_up ist 0
+	assertGolden(t, "prometheus_errors", scrapeDurations.ReplaceAll(out.Bytes(), []byte("${1} <t>")))
+}
+
+func TestPrometheusLabelEscaping(t *testing.T) {
+	var out bytes.Buffer
+	m := &metricWriter{w: &out}
+	m.sample("fe2_input_state", 1, "name", "Alarm \"Halle\"\nC:\\FE2", "id", "1")
+	want := `fe2_input_state{id="1",name="Alarm \"Halle\"\nC:\\FE2"} 1` + "\n"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestPrometheusCache(t *testing.T) {
+	simulator, config := newTestServer(t, loadFixture(t, "recorded"))
+	e := &exporter{config: config, cacheTTL: time.Minute}
+	for scrape := 0; scrape < 2; scrape++ {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		if !bytes.Contains(rec.Body.Bytes(), []byte(`fe2_up{endpoint="status"} 1`)) {
+			t.Errorf("scrape %d: status is not up\n%s", scrape, rec.Body.String())
+		}
+	}
+	// Der zweite Scrape innerhalb der TTL verwendet den Snapshot des ersten
+	if got := simulator.Requests("status"); got != 1 {
+		t.Errorf("status was requested %d times, want 1", got)
+	}
+
+	e.cacheTTL = 0
+	e.current()
+	if got := simulator.Requests("status"); got != 2 {
+		t.Errorf("status was requested %d times after the TTL, want 2", got)
+	}
+}
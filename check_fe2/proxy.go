@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ProxyConfig legt den HTTP-Proxy für die Anfragen an FE2 fest.
+// Ohne URL gelten die Umgebungsvariablen HTTP_PROXY, HTTPS_PROXY und NO_PROXY.
+type ProxyConfig struct {
+	// URL des Proxys, z.B. http://proxy.example.org:3128
+	URL string `yaml:"url"`
+	// Username und Password für die Anmeldung am Proxy, auch zusammen mit HTTP(S)_PROXY
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// NoProxy enthält kommagetrennte Hosts oder Domains (.example.org), die direkt erreicht werden
+	NoProxy string `yaml:"no_proxy"`
+	// Disabled verbindet immer direkt, auch wenn HTTP(S)_PROXY gesetzt ist
+	Disabled bool `yaml:"disabled"`
+}
+
+// newTransport erstellt den HTTP-Transport für FE2 mit den Proxy-Einstellungen der Konfiguration
+func newTransport(config Config) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		return proxyFor(config.Proxy, req)
+	}
+	return transport
+}
+
+// proxyFor liefert den Proxy für eine Anfrage oder nil für eine direkte Verbindung.
+// localhost und Loopback-Adressen werden immer direkt erreicht.
+func proxyFor(proxy ProxyConfig, req *http.Request) (*url.URL, error) {
+	if proxy.Disabled || isLoopback(req.URL.Hostname()) {
+		return nil, nil
+	}
+	var proxyURL *url.URL
+	if proxy.URL == "" {
+		var err error
+		if proxyURL, err = http.ProxyFromEnvironment(req); err != nil || proxyURL == nil {
+			return proxyURL, err
+		}
+	} else {
+		if noProxy(proxy.NoProxy, req.URL) {
+			return nil, nil
+		}
+		var err error
+		if proxyURL, err = url.Parse(proxy.URL); err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", proxy.URL)
+		}
+	}
+	if proxy.Username != "" {
+		// Die URL aus der Umgebung wird von net/http zwischengespeichert und geteilt, daher eine Kopie ändern
+		withUser := *proxyURL
+		withUser.User = url.UserPassword(proxy.Username, proxy.Password)
+		proxyURL = &withUser
+	}
+	return proxyURL, nil
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// noProxy prüft target gegen eine Liste im Format von NO_PROXY, z.B. "fe2.local,.example.org,10.0.0.1"
+func noProxy(list string, target *url.URL) bool {
+	host := strings.ToLower(target.Hostname())
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if h, port, err := net.SplitHostPort(entry); err == nil {
+			if port != target.Port() {
+				continue
+			}
+			entry = h
+		}
+		if host == strings.TrimPrefix(entry, ".") || strings.HasSuffix(host, "."+strings.TrimPrefix(entry, ".")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"checkmk_fe2/fe2sim"
+)
+
+// recordingTransport speichert jede Antwort der Monitoring-Schnittstelle im Verzeichnis dir (--record)
+type recordingTransport struct {
+	dir  string
+	next http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	recording := fe2sim.Recording{
+		Endpoint:       endpointFromPath(req.URL.Path),
+		Method:         req.Method,
+		URL:            req.URL.String(),
+		Time:           start,
+		DurationMs:     milliseconds(time.Since(start)),
+		RequestHeaders: fe2sim.RedactHeaders(req.Header),
+		Status:         resp.StatusCode,
+		Headers:        fe2sim.RedactHeaders(resp.Header),
+		Body:           string(body),
+	}
+	if err := fe2sim.SaveRecording(t.dir, recording); err != nil {
+		log.WithFields(endpointFields(recording.Endpoint)).WithError(err).Error("Error saving recording")
+	}
+	return resp, nil
+}
+
+// isolateReplay trennt die Konfiguration für --replay vom Zustand der überwachten Instanz:
+// Ohne cache_dir und Overrides schreibt die Wiedergabe weder capabilities.json noch circuit.json oder inputs.json
+func isolateReplay(config Config) Config {
+	config.CacheDir = ""
+	config.OverridesFile = ""
+	config.isolated = true
+	return config
+}
+
+// replayTransport beantwortet Anfragen offline aus einem mit --record erstellten Verzeichnis (--replay)
+type replayTransport struct {
+	simulator *fe2sim.Simulator
+}
+
+func newReplayTransport(dir string) (*replayTransport, error) {
+	fixture, err := fe2sim.LoadRecordings(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &replayTransport{simulator: fe2sim.New(fixture)}, nil
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	t.simulator.ServeHTTP(recorder, req)
+	return recorder.Result(), nil
+}
+
+// endpointFromPath liefert den Endpunkt relativ zur Monitoring-Schnittstelle, z.B. "input/<id>"
+func endpointFromPath(path string) string {
+	if i := strings.Index(path, fe2sim.BasePath); i >= 0 {
+		return path[i+len(fe2sim.BasePath):]
+	}
+	return strings.TrimPrefix(path, "/")
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	_, config := newTestServer(t, loadFixture(t, "recorded"))
+	config.Transport = &recordingTransport{dir: dir, next: http.DefaultTransport}
+	recorded, _ := runCheckmk(config, "all")
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// input, 3x input/<id>, amweb, cloud, status, mqtt
+	if len(files) != 8 {
+		t.Errorf("got %d recordings, want 8", len(files))
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), config.Token) {
+			t.Errorf("%s contains the token", file)
+		}
+	}
+
+	transport, err := newReplayTransport(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayConfig := Config{ApiURL: "http://fe2-replay/rest/monitoring/", Transport: transport}
+	replayed, endpoints := runCheckmk(replayConfig, "all")
+	for _, endpoint := range endpoints {
+		if endpoint.Err != nil {
+			t.Errorf("%s: unexpected error: %v", endpoint.Endpoint, endpoint.Err)
+		}
+	}
+	if !bytes.Equal(recorded, replayed) {
+		t.Errorf("replayed output differs\nrecorded:\n%s\nreplayed:\n%s", recorded, replayed)
+	}
+}
+
+func TestReplayIsolated(t *testing.T) {
+	dir := t.TempDir()
+	_, config := newTestServer(t, loadFixture(t, "recorded"))
+	config.Transport = &recordingTransport{dir: dir, next: http.DefaultTransport}
+	runCheckmk(config, "all")
+
+	transport, err := newReplayTransport(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Konfiguration der überwachten Instanz, die Wiedergabe darf deren Zustand nicht verändern
+	cacheDir := t.TempDir()
+	config.CacheDir = cacheDir
+	config.CircuitBreaker.Failures = 1
+	config.OverridesFile = filepath.Join(cacheDir, "overrides.yaml")
+	config.Transport = transport
+	config = isolateReplay(config)
+	runCheckmk(config, "all")
+
+	files, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		t.Errorf("replay wrote %s to the cache_dir", file.Name())
+	}
+	if path := overridesPath(config); path != "" {
+		t.Errorf("replay reads overrides from %s", path)
+	}
+}
+
+func TestRecordWithReplay(t *testing.T) {
+	record := t.TempDir()
+	args := []string{"--record", record, "--replay", t.TempDir()}
+	if exitCode := check("all", args); exitCode != StateUnknown {
+		t.Errorf("exit code = %d, want %d", exitCode, StateUnknown)
+	}
+	if files, _ := os.ReadDir(record); len(files) > 0 {
+		t.Errorf("%d recordings were written", len(files))
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// checkmk Status der Local Checks
+const (
+	StateOK      = 0
+	StateWarn    = 1
+	StateCrit    = 2
+	StateUnknown = 3
+	// StateDynamic überlässt checkmk die Berechnung anhand der Schwellwerte der Metriken ("P")
+	StateDynamic = -1
+)
+
+// Result ist ein Service im Sinne eines checkmk Local Checks
+type Result struct {
+	State   int      `json:"state"`
+	Name    string   `json:"name"`
+	Metrics []Metric `json:"metrics,omitempty"`
+	Summary string   `json:"summary"`
+	// Reason beschreibt, aus welchen Werten der Status abgeleitet wurde
+	Reason string `json:"reason,omitempty"`
+
+	// item sind die Daten des Eintrags, aus denen der Service erzeugt wurde, für die Regeln (rules)
+	item interface{}
+}
+
+// Metric ist ein Messwert eines Services mit optionalen oberen Schwellwerten
+type Metric struct {
+	Name   string  `json:"name"`
+	Value  float64 `json:"value"`
+	Levels bool    `json:"levels,omitempty"`
+	Warn   float64 `json:"warn,omitempty"`
+	Crit   float64 `json:"crit,omitempty"`
+	// LowerLevels warnt, wenn der Wert unter WarnLower bzw. CritLower fällt
+	LowerLevels bool    `json:"lowerLevels,omitempty"`
+	WarnLower   float64 `json:"warnLower,omitempty"`
+	CritLower   float64 `json:"critLower,omitempty"`
+}
+
+// EffectiveState liefert den Status des Services, bei StateDynamic berechnet aus den Schwellwerten
+func (r Result) EffectiveState() int {
+	if r.State != StateDynamic {
+		return r.State
+	}
+	state := StateOK
+	for _, metric := range r.Metrics {
+		if metric.Levels {
+			if metric.Value >= metric.Crit {
+				state = worstState(state, StateCrit)
+			} else if metric.Value >= metric.Warn {
+				state = worstState(state, StateWarn)
+			}
+		}
+		if metric.LowerLevels {
+			if metric.Value < metric.CritLower {
+				state = worstState(state, StateCrit)
+			} else if metric.Value < metric.WarnLower {
+				state = worstState(state, StateWarn)
+			}
+		}
+	}
+	return state
+}
+
+// String liefert die Metrik im Format name=value;warn;crit,
+// mit unteren Schwellwerten als Bereich name=value;warnLower:warn;critLower:crit
+func (m Metric) String() string {
+	if !m.Levels && !m.LowerLevels {
+		return m.Name + "=" + formatValue(m.Value)
+	}
+	if !m.LowerLevels {
+		return m.Name + "=" + formatValue(m.Value) + ";" + formatValue(m.Warn) + ";" + formatValue(m.Crit)
+	}
+	warn, crit := formatValue(m.WarnLower)+":", formatValue(m.CritLower)+":"
+	if m.Levels {
+		warn += formatValue(m.Warn)
+		crit += formatValue(m.Crit)
+	}
+	return m.Name + "=" + formatValue(m.Value) + ";" + warn + ";" + crit
+}
+
+// CheckmkLine liefert die Zeile für die Local Check Ausgabe
+func (r Result) CheckmkLine() string {
+	state := strconv.Itoa(r.State)
+	if r.State == StateDynamic {
+		state = "P"
+	}
+	perfdata := "-"
+	if len(r.Metrics) > 0 {
+		var metrics []string
+		for _, metric := range r.Metrics {
+			metrics = append(metrics, metric.String())
+		}
+		perfdata = strings.Join(metrics, "|")
+	}
+	return fmt.Sprintf("%s \"%s\" %s %s", state, r.Name, perfdata, r.Summary)
+}
+
+var stateNames = map[int]string{
+	StateOK:      "OK",
+	StateWarn:    "WARN",
+	StateCrit:    "CRIT",
+	StateUnknown: "UNKNOWN",
+	StateDynamic: "P",
+}
+
+// worstState liefert den schlechteren von zwei Status (OK < WARN < UNKNOWN < CRIT)
+func worstState(a, b int) int {
+	if stateSeverity(b) > stateSeverity(a) {
+		return b
+	}
+	return a
+}
+
+func stateSeverity(state int) int {
+	switch state {
+	case StateWarn:
+		return 1
+	case StateUnknown:
+		return 2
+	case StateCrit:
+		return 3
+	}
+	return 0
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Rule berechnet den Zustand eines Services aus einem Ausdruck (rules), die erste zutreffende Regel gilt
+type Rule struct {
+	// When ist der Ausdruck, z.B. connectionState != "OK" && nbrOfWebSocketConnections == 0
+	When string `yaml:"when"`
+	// State ist OK, WARN, CRIT, UNKNOWN oder 0 bis 3
+	State string `yaml:"state"`
+}
+
+type compiledRule struct {
+	Rule
+	expr  exprNode
+	state int
+}
+
+var ruleStates = map[string]int{"OK": StateOK, "WARN": StateWarn, "CRIT": StateCrit, "UNKNOWN": StateUnknown}
+
+// compileRules übersetzt die Regeln eines Endpunkts
+func compileRules(rules []Rule) ([]compiledRule, error) {
+	var compiled []compiledRule
+	for i, rule := range rules {
+		expr, err := parseExpr(rule.When)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		state, ok := ruleStates[strings.ToUpper(rule.State)]
+		if number, err := strconv.Atoi(rule.State); err == nil && number >= StateOK && number <= StateUnknown {
+			state, ok = number, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("rule %d: invalid state %q", i+1, rule.State)
+		}
+		compiled = append(compiled, compiledRule{Rule: rule, expr: expr, state: state})
+	}
+	return compiled, nil
+}
+
+// validateRules prüft die Regeln aller Endpunkte beim Lesen der Konfiguration,
+// Regeln für unbekannte Endpunkte (z.B. Tippfehler wie amwebs) sind ein Fehler
+func validateRules(config Config) error {
+	for endpoint, list := range config.Rules {
+		if len(collectorNames(config, endpoint)) == 0 {
+			return fmt.Errorf("rules for unknown endpoint %q", endpoint)
+		}
+		if _, err := compileRules(list); err != nil {
+			return fmt.Errorf("rules for %s: %w", endpoint, err)
+		}
+	}
+	return nil
+}
+
+// newRuleEnv stellt die Daten eines Services für die Ausdrücke bereit, Felder heißen wie im JSON von FE2
+func newRuleEnv(result Result) ruleEnv {
+	item := result.item
+	if data, err := json.Marshal(item); err == nil {
+		json.Unmarshal(data, &item)
+	}
+	return ruleEnv{item: item, service: result.Name, state: result.EffectiveState()}
+}
+
+// applyRules setzt den Zustand der Services eines Endpunkts nach der ersten zutreffenden Regel.
+// Services ohne Daten eines Eintrags, z.B. die Zusammenfassung je Organisation, bleiben unverändert.
+func applyRules(config Config, endpoint string, results []Result) []Result {
+	rules, err := compileRules(config.Rules[endpoint])
+	if err != nil {
+		config.logger().WithField("endpoint", endpoint).WithError(err).Warn("Error compiling rules")
+		return results
+	}
+	if len(rules) == 0 {
+		return results
+	}
+	for i, result := range results {
+		if result.item == nil {
+			continue
+		}
+		env := newRuleEnv(result)
+		for _, rule := range rules {
+			if truthy(rule.expr.eval(env)) {
+				results[i].State = rule.state
+				results[i].Reason = "Regel: " + rule.When
+				break
+			}
+		}
+	}
+	return results
+}
+
+// explain zeigt für jeden Service, welche Regel zutrifft (check_fe2 explain [endpoint])
+func explain(config Config, args []string, w io.Writer) int {
+	command := "all"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	names := collectorNames(config, command)
+	if len(names) == 0 {
+		fmt.Fprintf(w, "Unbekannter Endpunkt %q\n", command)
+		return StateUnknown
+	}
+	for _, collector := range configCollectors(config) {
+		name := collector.Name()
+		if !containsString(names, name) {
+			continue
+		}
+		fmt.Fprintf(w, "== %s\n", name)
+		rules, err := compileRules(config.Rules[name])
+		if err != nil {
+			fmt.Fprintf(w, "Regeln fehlerhaft: %v\n\n", err)
+			continue
+		}
+		_, results, err := runCollector(config, collector)
+		if err != nil {
+			fmt.Fprintf(w, "Abfrage fehlgeschlagen: %v\n", err)
+		}
+		if len(rules) == 0 {
+			fmt.Fprintln(w, "Keine Regeln, es gilt die eingebaute Auswertung")
+		}
+		for _, result := range results {
+			explainResult(w, result, rules)
+		}
+		fmt.Fprintln(w)
+	}
+	return StateOK
+}
+
+func explainResult(w io.Writer, result Result, rules []compiledRule) {
+	fmt.Fprintf(w, "%s\n", result.Name)
+	builtin := stateNames[result.EffectiveState()]
+	if result.Reason != "" {
+		builtin += " (" + result.Reason + ")"
+	}
+	fmt.Fprintf(w, "  eingebaut:  %s\n", builtin)
+	if result.item == nil {
+		if len(rules) > 0 {
+			fmt.Fprintln(w, "  Regeln gelten nicht für diesen Service")
+		}
+		return
+	}
+	env := newRuleEnv(result)
+	final := stateNames[result.EffectiveState()] + " (keine Regel trifft zu)"
+	matched := false
+	for i, rule := range rules {
+		if matched {
+			fmt.Fprintf(w, "  Regel %d:    %s -> %s: nicht geprüft\n", i+1, rule.When, stateNames[rule.state])
+			continue
+		}
+		verdict := "trifft nicht zu"
+		if truthy(rule.expr.eval(env)) {
+			verdict = "TRIFFT ZU"
+			final = fmt.Sprintf("%s (Regel %d)", stateNames[rule.state], i+1)
+			matched = true
+		}
+		fmt.Fprintf(w, "  Regel %d:    %s -> %s: %s\n", i+1, rule.When, stateNames[rule.state], verdict)
+	}
+	fmt.Fprintf(w, "  Ergebnis:   %s\n", final)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExpressions(t *testing.T) {
+	env := ruleEnv{
+		item: map[string]interface{}{
+			"state":                     "ERROR",
+			"message":                   "IMAP Timeout nach 30s",
+			"nbrOfWebSocketConnections": 0.0,
+			"redundancyState":           map[string]interface{}{"current": "MASTER"},
+			"tags":                      []interface{}{"backup"},
+		},
+		service: "FE2 Input: Mail",
+		state:   StateWarn,
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`state == "ERROR" && message contains "Timeout"`, true},
+		{`state != "OK" && nbrOfWebSocketConnections == 0`, true},
+		{`nbrOfWebSocketConnections > 0 || redundancyState.current == 'MASTER'`, true},
+		{`!(state == "ERROR")`, false},
+		{`message matches "^IMAP .* [0-9]+s$"`, true},
+		{`tags contains "backup" && @service contains "Mail" && @state >= 1`, true},
+		{`missing == null && !missing`, true},
+		{`state < 3`, false},
+		{`message contains "Störung €"`, false},
+		{`größe == null`, true},
+	}
+	for _, test := range tests {
+		node, err := parseExpr(test.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", test.expr, err)
+		}
+		if got := truthy(node.eval(env)); got != test.want {
+			t.Errorf("%s = %v, want %v", test.expr, got, test.want)
+		}
+	}
+
+	for _, invalid := range []string{`state ==`, `(state == "OK"`, `state == "OK`, `message matches state`, `message matches "("`, `state # 1`, `message contains „Timeout“`, `state == "OK" € 1`} {
+		if _, err := parseExpr(invalid); err == nil {
+			t.Errorf("%s: expected error", invalid)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	_, config := newTestServer(t, loadFixture(t, "recorded"))
+	config.AmwebOrganisations = true
+	config.Rules = map[string][]Rule{
+		"amweb": {
+			{When: `connectionState != "OK" && nbrOfWebSocketConnections == 0 && connectionType == "WEBSOCKET"`, State: "CRIT"},
+			{When: `connectionType == "POLLING"`, State: "OK"},
+		},
+		"input": {{When: `state == "ERROR" && message contains "Timeout"`, State: "2"}},
+	}
+	if err := validateRules(config); err != nil {
+		t.Fatal(err)
+	}
+
+	out, _ := runCheckmk(config, "all")
+	for _, want := range []string{
+		`2 "AmWeb: AMweb Wache Süd" connection=0`,
+		`0 "AmWeb: AMweb Gerätehaus" connection=0`,
+		`0 "AmWeb: AMweb Wache Nord" connection=3`,
+		`2 "FE2 Input: E-Mail Leitstelle" - Verbindung zum IMAP Server fehlgeschlagen: Timeout`,
+		// Die Zusammenfassung je Organisation ist kein Eintrag von FE2 und bleibt unverändert
+		`2 "AmWeb Organisation: FF Musterdorf"`,
+	} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	var report bytes.Buffer
+	explain(config, []string{"amweb"}, &report)
+	for _, want := range []string{
+		"AmWeb: AMweb Wache Süd\n  eingebaut:  WARN",
+		"Regel 1:    connectionState != \"OK\" && nbrOfWebSocketConnections == 0 && connectionType == \"WEBSOCKET\" -> CRIT: TRIFFT ZU",
+		"Regel 2:    connectionType == \"POLLING\" -> OK: nicht geprüft",
+		"Ergebnis:   CRIT (Regel 1)",
+		"Ergebnis:   OK (keine Regel trifft zu)",
+	} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("explain does not contain %q:\n%s", want, report.String())
+		}
+	}
+
+	if err := validateRules(Config{Rules: map[string][]Rule{"amweb": {{When: `state == "OK"`, State: "BROKEN"}}}}); err == nil {
+		t.Error("expected error for invalid state")
+	}
+	if err := validateRules(Config{Rules: map[string][]Rule{"amwebs": {{When: `state == "OK"`, State: "OK"}}}}); err == nil {
+		t.Error("expected error for unknown endpoint")
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// schemaReport sammelt Abweichungen zwischen den Antworten von FE2 und den erwarteten Strukturen (schema_check)
+type schemaReport struct {
+	mu       sync.Mutex
+	findings map[string]map[string]bool
+}
+
+func newSchemaReport() *schemaReport {
+	return &schemaReport{findings: make(map[string]map[string]bool)}
+}
+
+// check vergleicht den JSON-Body eines Endpunkts mit dem Typ von target
+func (s *schemaReport) check(endpoint string, body []byte, target interface{}) {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		// Ungültiges JSON wird bereits als Fehler des Endpunkts gemeldet
+		return
+	}
+	var findings []string
+	compareSchema("", value, reflect.TypeOf(target), &findings)
+
+	// Die Details der Eingänge werden unter einem gemeinsamen Namen zusammengefasst
+	if strings.HasPrefix(endpoint, "input/") {
+		endpoint = "input/<id>"
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.findings[endpoint] == nil {
+		s.findings[endpoint] = make(map[string]bool)
+	}
+	for _, finding := range findings {
+		s.findings[endpoint][finding] = true
+	}
+}
+
+// result liefert den Service "FE2 API Schema", WARN bei neuen, fehlenden oder geänderten Feldern
+func (s *schemaReport) result() Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var details []string
+	for endpoint, findings := range s.findings {
+		if len(findings) == 0 {
+			continue
+		}
+		var list []string
+		for finding := range findings {
+			list = append(list, finding)
+		}
+		sort.Strings(list)
+		details = append(details, endpoint+": "+strings.Join(list, ", "))
+	}
+	sort.Strings(details)
+	if len(details) == 0 {
+		return Result{State: StateOK, Name: "FE2 API Schema", Summary: "Die Antworten entsprechen den erwarteten Feldern"}
+	}
+	return Result{
+		State:   StateWarn,
+		Name:    "FE2 API Schema",
+		Reason:  "Abweichungen in den Antworten der Monitoring-Schnittstelle",
+		Summary: strings.Join(details, "; "),
+	}
+}
+
+// optionalFields sind Felder, die FE2 nicht in jeder Antwort liefert und deren Fehlen keine Abweichung ist.
+// Sie stehen hier und nicht in den Struct-Tags, damit die JSON-Ausgabe unverändert bleibt.
+var optionalFields = map[reflect.Type][]string{
+	// /input/{id} enthält die ID nicht, sie wird aus der Liste übernommen
+	reflect.TypeOf(InputServiceDetail{}): {"id"},
+}
+
+// compareSchema vergleicht einen dekodierten JSON-Wert rekursiv mit dem erwarteten Go-Typ
+func compareSchema(path string, value interface{}, t reflect.Type, findings *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if value == nil || t.Kind() == reflect.Interface {
+		return
+	}
+	if got, want := jsonType(value), expectedJSONType(t); got != want {
+		*findings = append(*findings, fmt.Sprintf("Typ geändert: %s (%s statt %s)", pathName(path), got, want))
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		for _, item := range value.([]interface{}) {
+			compareSchema(path+"[]", item, t.Elem(), findings)
+		}
+	case reflect.Struct:
+		object := value.(map[string]interface{})
+		known := make(map[string]bool)
+		for i := 0; i < t.NumField(); i++ {
+			name, ok := jsonField(t.Field(i))
+			if !ok {
+				continue
+			}
+			known[name] = true
+			fieldValue, present := object[name]
+			if !present {
+				if !containsString(optionalFields[t], name) {
+					*findings = append(*findings, "fehlt: "+joinPath(path, name))
+				}
+				continue
+			}
+			compareSchema(joinPath(path, name), fieldValue, t.Field(i).Type, findings)
+		}
+		for name := range object {
+			if !known[name] {
+				*findings = append(*findings, "neu: "+joinPath(path, name))
+			}
+		}
+	}
+}
+
+// jsonField liefert den JSON-Namen eines Feldes
+func jsonField(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "null"
+}
+
+func expectedJSONType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	}
+	return t.Kind().String()
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func pathName(path string) string {
+	if path == "" {
+		return "<root>"
+	}
+	return path
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"checkmk_fe2/fe2sim"
+)
+
+func schemaResult(t *testing.T, fixture fe2sim.Fixture) Result {
+	t.Helper()
+	_, config := newTestServer(t, fixture)
+	config.SchemaCheck = true
+	for _, endpoint := range runChecks(config, "all") {
+		if endpoint.Endpoint == "schema" {
+			return endpoint.Results[0]
+		}
+	}
+	t.Fatal("no schema service")
+	return Result{}
+}
+
+func TestSchemaRecorded(t *testing.T) {
+	result := schemaResult(t, loadFixture(t, "recorded"))
+	if result.State != StateOK {
+		t.Errorf("state = %d, want OK: %s", result.State, result.Summary)
+	}
+}
+
+func TestSchemaDrift(t *testing.T) {
+	fixture := copyFixture(loadFixture(t, "recorded"))
+	setEndpoint(&fixture, "amweb", fe2sim.Response{Raw: `[{"identifier":"AM-4711","name":"AMweb Wache Nord","organization":"FF Musterstadt","connectionType":"WEBSOCKET","connectionState":"OK","nbrOfConnections":3}]`})
+	setEndpoint(&fixture, "status", fe2sim.Response{Raw: `{"state":"OK","message":"","nbrOfLoggedErrors":"3","redundancyState":{"state":"OK","current":"MASTER","configured":"MASTER"}}`})
+
+	result := schemaResult(t, fixture)
+	want := "amweb: fehlt: [].nbrOfWebSocketConnections, neu: [].nbrOfConnections; status: Typ geändert: nbrOfLoggedErrors (string statt number)"
+	if result.State != StateWarn || result.Summary != want {
+		t.Errorf("got %d %q\nwant %d %q", result.State, result.Summary, StateWarn, want)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"time"
+)
+
+// Snapshot enthält die dekodierten Antworten aller Endpunkte eines Abrufs
+type Snapshot struct {
+	Time      time.Time
+	Inputs    []InputServiceDetail
+	Amwebs    []Amweb
+	Cloud     []CloudService
+	Status    Status
+	Mqtt      Mqtt
+	Endpoints []EndpointScrape
+}
+
+// EndpointScrape beschreibt Dauer und Ergebnis des Abrufs eines Endpunkts
+type EndpointScrape struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// collectSnapshot ruft alle Endpunkte der Monitoring-Schnittstelle nacheinander ab
+func collectSnapshot(config Config) Snapshot {
+	snapshot := Snapshot{Time: time.Now()}
+	snapshot.scrape(config, "input", func() (err error) {
+		snapshot.Inputs, err = fetchInputs(config)
+		return err
+	})
+	snapshot.scrape(config, "amweb", func() (err error) {
+		snapshot.Amwebs, err = fetchAmwebs(config)
+		return err
+	})
+	snapshot.scrape(config, "cloud", func() (err error) {
+		snapshot.Cloud, err = fetchCloud(config)
+		return err
+	})
+	snapshot.scrape(config, "status", func() (err error) {
+		snapshot.Status, err = fetchStatus(config)
+		return err
+	})
+	snapshot.scrape(config, "mqtt", func() (err error) {
+		snapshot.Mqtt, err = fetchMqtt(config)
+		return err
+	})
+	return snapshot
+}
+
+// scrape ruft einen Endpunkt ab, in der Konfiguration deaktivierte Endpunkte werden übersprungen
+func (s *Snapshot) scrape(config Config, name string, fetch func() error) {
+	if !config.endpointEnabled(name) {
+		return
+	}
+	start := time.Now()
+	err := fetch()
+	if err != nil {
+		logCheckFailure(config, name, err)
+	}
+	s.Endpoints = append(s.Endpoints, EndpointScrape{Name: name, Duration: time.Since(start), Err: err})
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	// Zeitzonen auch unter Windows, wo keine Zeitzonen-Datenbank installiert ist
+	_ "time/tzdata"
+)
+
+// ThresholdConfig enthält Schwellwerte, die je nach Zeitfenster (z.B. Nacht oder Wochenende) abweichen können
+type ThresholdConfig struct {
+	// Timezone der Zeitfenster, z.B. Europe/Berlin, Standard ist die lokale Zeitzone
+	Timezone     string `yaml:"timezone"`
+	ThresholdSet `yaml:",inline"`
+	// Windows überschreiben die Schwellwerte, das erste passende Zeitfenster gilt
+	Windows []ThresholdWindow `yaml:"windows"`
+}
+
+// ThresholdSet sind die Schwellwerte, die in einem Zeitfenster überschrieben werden können
+type ThresholdSet struct {
+	// StatusErrors gilt für nbrOfLoggedErrors im Service "FE2 Selfstatus", Standard 1/5
+	StatusErrors *Levels `yaml:"status_errors"`
+	// AmwebConnections gilt für nbrOfWebSocketConnections je AMweb Gerät, z.B. warn_below: 2
+	AmwebConnections *Levels `yaml:"amweb_connections"`
+}
+
+// ThresholdWindow ist ein wiederkehrendes Zeitfenster mit eigenen Schwellwerten
+type ThresholdWindow struct {
+	Name string `yaml:"name"`
+	// Days, z.B. [mon-fri] oder [sat, sun], ohne Angabe jeden Tag
+	Days []string `yaml:"days"`
+	// From und To im Format HH:MM, ohne Angabe der ganze Tag. Liegt To vor From, geht das Zeitfenster
+	// über Mitternacht und gehört zum Tag, an dem es beginnt.
+	From         string `yaml:"from"`
+	To           string `yaml:"to"`
+	ThresholdSet `yaml:",inline"`
+}
+
+// Levels sind die Schwellwerte einer Metrik: Warn/Crit nach oben, WarnBelow/CritBelow nach unten
+type Levels struct {
+	Warn      *float64 `yaml:"warn"`
+	Crit      *float64 `yaml:"crit"`
+	WarnBelow *float64 `yaml:"warn_below"`
+	CritBelow *float64 `yaml:"crit_below"`
+}
+
+// activeLevels sind die zu einem Zeitpunkt gültigen Schwellwerte
+type activeLevels struct {
+	statusErrors     Levels
+	amwebConnections Levels
+	// window ist der Name des aktiven Zeitfensters, leer für die Standardwerte
+	window string
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func float(value float64) *float64 {
+	return &value
+}
+
+// activeThresholds liefert die Schwellwerte für den Zeitpunkt now
+func activeThresholds(config ThresholdConfig, now time.Time) activeLevels {
+	active := activeLevels{statusErrors: Levels{Warn: float(1), Crit: float(5)}}
+	active.statusErrors = active.statusErrors.merge(config.StatusErrors)
+	active.amwebConnections = active.amwebConnections.merge(config.AmwebConnections)
+
+	if location, err := loadTimezone(config.Timezone); err == nil {
+		now = now.In(location)
+	}
+	for i, window := range config.Windows {
+		if !window.contains(now) {
+			continue
+		}
+		active.statusErrors = active.statusErrors.merge(window.StatusErrors)
+		active.amwebConnections = active.amwebConnections.merge(window.AmwebConnections)
+		active.window = window.Name
+		if active.window == "" {
+			active.window = fmt.Sprintf("Zeitfenster %d", i+1)
+		}
+		break
+	}
+	return active
+}
+
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// contains prüft, ob now (in der Zeitzone der Konfiguration) im Zeitfenster liegt
+func (w ThresholdWindow) contains(now time.Time) bool {
+	minute := now.Hour()*60 + now.Minute()
+	from, _ := parseClock(w.From, 0)
+	to, _ := parseClock(w.To, 24*60)
+	switch {
+	case from < to:
+		return w.onDay(now.Weekday()) && minute >= from && minute < to
+	case from > to:
+		// Über Mitternacht: der Teil nach Mitternacht gehört zum Vortag
+		return (w.onDay(now.Weekday()) && minute >= from) || (w.onDay((now.Weekday()+6)%7) && minute < to)
+	}
+	return false
+}
+
+func (w ThresholdWindow) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, entry := range w.Days {
+		first, last, _ := parseDayRange(entry)
+		for d := first; ; d = (d + 1) % 7 {
+			if d == day {
+				return true
+			}
+			if d == last {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// parseDayRange liest einen Tag wie "mon" oder einen Bereich wie "mon-fri"
+func parseDayRange(entry string) (time.Weekday, time.Weekday, error) {
+	parts := strings.SplitN(strings.ToLower(strings.TrimSpace(entry)), "-", 2)
+	first, ok := weekdays[parts[0]]
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid day %q, use mon, tue, wed, thu, fri, sat or sun", entry)
+	}
+	last := first
+	if len(parts) == 2 {
+		if last, ok = weekdays[parts[1]]; !ok {
+			return 0, 0, fmt.Errorf("invalid day %q, use mon, tue, wed, thu, fri, sat or sun", entry)
+		}
+	}
+	return first, last, nil
+}
+
+// parseClock liefert eine Uhrzeit HH:MM als Minuten seit Mitternacht, ohne Angabe fallback
+func parseClock(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return fallback, fmt.Errorf("invalid time %q, use HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// validateThresholds prüft Zeitzone, Zeitfenster und Schwellwerte beim Lesen der Konfiguration
+func validateThresholds(config ThresholdConfig) error {
+	if _, err := loadTimezone(config.Timezone); err != nil {
+		return fmt.Errorf("thresholds: invalid timezone %q: %w", config.Timezone, err)
+	}
+	if err := config.ThresholdSet.validate(); err != nil {
+		return fmt.Errorf("thresholds: %w", err)
+	}
+	for i, window := range config.Windows {
+		label := fmt.Sprintf("thresholds window %d", i+1)
+		if window.Name != "" {
+			label = fmt.Sprintf("thresholds window %q", window.Name)
+		}
+		for _, day := range window.Days {
+			if _, _, err := parseDayRange(day); err != nil {
+				return fmt.Errorf("%s: %w", label, err)
+			}
+		}
+		from, err := parseClock(window.From, 0)
+		if err != nil {
+			return fmt.Errorf("%s: %w", label, err)
+		}
+		to, err := parseClock(window.To, 24*60)
+		if err != nil {
+			return fmt.Errorf("%s: %w", label, err)
+		}
+		if from == to {
+			return fmt.Errorf("%s: from and to are equal, the window would never match", label)
+		}
+		if err := window.ThresholdSet.validate(); err != nil {
+			return fmt.Errorf("%s: %w", label, err)
+		}
+	}
+	return nil
+}
+
+func (s ThresholdSet) validate() error {
+	if err := s.StatusErrors.validate(); err != nil {
+		return fmt.Errorf("status_errors: %w", err)
+	}
+	if err := s.AmwebConnections.validate(); err != nil {
+		return fmt.Errorf("amweb_connections: %w", err)
+	}
+	return nil
+}
+
+// validate verlangt Schwellwerte paarweise und in der richtigen Reihenfolge
+func (l *Levels) validate() error {
+	if l == nil {
+		return nil
+	}
+	if (l.Warn == nil) != (l.Crit == nil) {
+		return fmt.Errorf("warn and crit must be set together")
+	}
+	if (l.WarnBelow == nil) != (l.CritBelow == nil) {
+		return fmt.Errorf("warn_below and crit_below must be set together")
+	}
+	if l.upper() && *l.Warn > *l.Crit {
+		return fmt.Errorf("warn %g is above crit %g", *l.Warn, *l.Crit)
+	}
+	if l.lower() && *l.CritBelow > *l.WarnBelow {
+		return fmt.Errorf("crit_below %g is above warn_below %g", *l.CritBelow, *l.WarnBelow)
+	}
+	return nil
+}
+
+// merge übernimmt die in other gesetzten Werte
+func (l Levels) merge(other *Levels) Levels {
+	if other == nil {
+		return l
+	}
+	if other.Warn != nil {
+		l.Warn = other.Warn
+	}
+	if other.Crit != nil {
+		l.Crit = other.Crit
+	}
+	if other.WarnBelow != nil {
+		l.WarnBelow = other.WarnBelow
+	}
+	if other.CritBelow != nil {
+		l.CritBelow = other.CritBelow
+	}
+	return l
+}
+
+func (l Levels) upper() bool {
+	return l.Warn != nil && l.Crit != nil
+}
+
+func (l Levels) lower() bool {
+	return l.WarnBelow != nil && l.CritBelow != nil
+}
+
+// apply setzt die Schwellwerte einer Metrik, unvollständige Paare werden ignoriert
+func (l Levels) apply(metric Metric) Metric {
+	if l.upper() {
+		metric.Levels, metric.Warn, metric.Crit = true, *l.Warn, *l.Crit
+	}
+	if l.lower() {
+		metric.LowerLevels, metric.WarnLower, metric.CritLower = true, *l.WarnBelow, *l.CritBelow
+	}
+	return metric
+}
+
+// String beschreibt die Schwellwerte für den Reason eines Services, z.B. "1/5" oder "unten 2/1"
+func (l Levels) String() string {
+	var parts []string
+	if l.upper() {
+		parts = append(parts, formatValue(*l.Warn)+"/"+formatValue(*l.Crit))
+	}
+	if l.lower() {
+		parts = append(parts, "unten "+formatValue(*l.WarnBelow)+"/"+formatValue(*l.CritBelow))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// describeWindow ergänzt den Namen des aktiven Zeitfensters
+func (a activeLevels) describeWindow() string {
+	if a.window == "" {
+		return ""
+	}
+	return " (" + a.window + ")"
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const thresholdsYAML = `
+timezone: Europe/Berlin
+amweb_connections:
+  warn_below: 2
+  crit_below: 1
+windows:
+  - name: Nacht
+    days: [mon-fri]
+    from: "22:00"
+    to: "06:00"
+    status_errors: {warn: 10, crit: 20}
+    amweb_connections: {warn_below: 0, crit_below: 0}
+  - name: Wochenende
+    days: [sat, sun]
+    status_errors: {warn: 1, crit: 50}
+`
+
+func TestActiveThresholds(t *testing.T) {
+	var config ThresholdConfig
+	if err := yaml.Unmarshal([]byte(thresholdsYAML), &config); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateThresholds(config); err != nil {
+		t.Fatal(err)
+	}
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	tests := []struct {
+		time   string
+		window string
+		errors string
+	}{
+		{"2026-10-19 12:00", "", "1/5"},            // Montag Mittag
+		{"2026-10-19 23:30", "Nacht", "10/20"},     // Montag Nacht
+		{"2026-10-20 05:59", "Nacht", "10/20"},     // Dienstag früh, Fenster vom Montag
+		{"2026-10-19 05:00", "", "1/5"},            // Montag früh, Sonntag ist kein Nachtfenster
+		{"2026-10-24 23:30", "Wochenende", "1/50"}, // Samstag
+		{"2026-10-24 02:00", "Nacht", "10/20"},     // Samstag früh, Fenster vom Freitag
+	}
+	for _, test := range tests {
+		now, err := time.ParseInLocation("2006-01-02 15:04", test.time, berlin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Die Zeitzone der Konfiguration gilt unabhängig von der Zeitzone des Systems
+		active := activeThresholds(config, now.UTC())
+		if active.window != test.window || active.statusErrors.String() != test.errors {
+			t.Errorf("%s: window %q errors %s, want %q %s", test.time, active.window, active.statusErrors, test.window, test.errors)
+		}
+	}
+
+	for _, invalid := range []string{
+		"timezone: Mars/Olympus",
+		"windows: [{days: [monday]}]",
+		"windows: [{from: '25:00'}]",
+		"windows: [{name: Nacht, from: '22:00', to: '22:00'}]",
+		"windows: [{name: Nacht, status_errors: {crit: 50}}]",
+		"windows: [{amweb_connections: {warn_below: 2}}]",
+		"windows: [{status_errors: {warn: 20, crit: 10}}]",
+		"amweb_connections: {warn_below: 1, crit_below: 2}",
+		"status_errors: {warn: 5}",
+	} {
+		var config ThresholdConfig
+		yaml.Unmarshal([]byte(invalid), &config)
+		if err := validateThresholds(config); err == nil {
+			t.Errorf("%s: expected error", invalid)
+		}
+	}
+}
+
+func TestAmwebLowerLevels(t *testing.T) {
+	config := Config{Thresholds: ThresholdConfig{ThresholdSet: ThresholdSet{AmwebConnections: &Levels{WarnBelow: float(2), CritBelow: float(1)}}}}
+	results := evaluateAmwebs(config, []Amweb{
+		{Name: "Nord", ConnectionState: "OK", ConnectionsCount: 3},
+		{Name: "Süd", ConnectionState: "OK", ConnectionsCount: 1},
+		{Name: "Dorf", ConnectionState: "OK", ConnectionsCount: 0},
+	})
+	want := []string{
+		`0 "AmWeb: Nord" connection=3;2:;1: `,
+		`1 "AmWeb: Süd" connection=1;2:;1: `,
+		`2 "AmWeb: Dorf" connection=0;2:;1: `,
+	}
+	for i, result := range results {
+		if !strings.HasPrefix(result.CheckmkLine(), want[i]) {
+			t.Errorf("got %q, want prefix %q", result.CheckmkLine(), want[i])
+		}
+	}
+
+	both := Metric{Name: "m", Value: 7, Levels: true, Warn: 8, Crit: 9, LowerLevels: true, WarnLower: 2, CritLower: 1}
+	if got := both.String(); got != "m=7;2:8;1:9" {
+		t.Errorf("metric = %s, want m=7;2:8;1:9", got)
+	}
+}
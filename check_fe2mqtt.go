@@ -62,9 +62,9 @@ func main() {
 		defaultstate = 3
 	}
 	kubernetesstate := 0
-	if services.Defaultbroker == "ERROR" {
+	if services.Kubernetes == "ERROR" {
 		kubernetesstate = 1
-	} else if services.Defaultbroker == "NOT_USED" {
+	} else if services.Kubernetes == "NOT_USED" {
 		kubernetesstate = 3
 	}
 
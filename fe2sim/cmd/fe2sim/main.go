@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"checkmk_fe2/fe2sim"
+)
+
+// Aufruf: fe2sim --fixture fe2sim/fixtures/example.yaml --listen :8083
+func main() {
+	listen := flag.String("listen", ":8083", "address to listen on")
+	fixtureFile := flag.String("fixture", "fe2sim/fixtures/example.yaml", "YAML or JSON fixture file")
+	token := flag.String("token", "", "overrides the token of the fixture")
+	flag.Parse()
+
+	fixture, err := fe2sim.Load(*fixtureFile)
+	if err != nil {
+		log.WithError(err).Fatal("Error loading fixture")
+	}
+	if *token != "" {
+		fixture.Token = *token
+	}
+
+	simulator := fe2sim.New(fixture)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.WithField("path", r.URL.Path).Info("Request")
+		simulator.ServeHTTP(w, r)
+	})
+	log.WithField("listen", *listen).Info("Starting FE2 monitoring simulator")
+	if err := http.ListenAndServe(*listen, handler); err != nil {
+		log.WithError(err).Fatal("Error running simulator")
+	}
+}
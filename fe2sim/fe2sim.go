@@ -0,0 +1,202 @@
+// Package fe2sim simuliert die Monitoring-Schnittstelle eines FE2 Servers (/rest/monitoring/...)
+// anhand von Fixture-Dateien, damit die Checks ohne FE2 Server getestet werden können.
+package fe2sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// BasePath ist der Pfad der Monitoring-Schnittstelle
+const BasePath = "/rest/monitoring/"
+
+// Fixture beschreibt die Antworten der simulierten Endpunkte.
+// Die Schlüssel von Endpoints sind relativ zu BasePath, z.B. "input", "input/<id>" oder "amweb".
+type Fixture struct {
+	// Token wird im Authorization-Header erwartet, leer bedeutet keine Prüfung
+	Token     string              `yaml:"token"`
+	Endpoints map[string]Endpoint `yaml:"endpoints"`
+}
+
+// Endpoint beschreibt die Antwort eines Endpunkts.
+// Ist Sequence gesetzt, werden die Antworten reihum ausgeliefert (flappende Zustände).
+type Endpoint struct {
+	Response `yaml:",inline"`
+	Sequence []Response `yaml:"sequence"`
+}
+
+// Response ist eine einzelne Antwort der Schnittstelle
+type Response struct {
+	// Status ist der HTTP-Statuscode, Standard ist 200
+	Status int `yaml:"status"`
+	// Delay verzögert die Antwort, z.B. "2s"
+	Delay time.Duration `yaml:"delay"`
+	// Body wird als JSON ausgeliefert
+	Body interface{} `yaml:"body"`
+	// Raw wird unverändert ausgeliefert und hat Vorrang vor Body
+	Raw string `yaml:"raw"`
+	// Malformed liefert absichtlich fehlerhaftes JSON
+	Malformed bool `yaml:"malformed"`
+	// Empty liefert eine Antwort ohne Body, z.B. eine aufgezeichnete leere Antwort
+	Empty bool `yaml:"empty"`
+}
+
+// Load liest eine Fixture-Datei im YAML- oder JSON-Format
+func Load(filename string) (Fixture, error) {
+	var fixture Fixture
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fixture, err
+	}
+	if err := yaml.Unmarshal(data, &fixture); err != nil {
+		return fixture, fmt.Errorf("error decoding fixture %s: %w", filename, err)
+	}
+	return fixture, nil
+}
+
+// Simulator liefert die Antworten einer Fixture als http.Handler aus
+type Simulator struct {
+	mu           sync.Mutex
+	fixture      Fixture
+	requests     map[string]int
+	unknown      map[string]int
+	unauthorized int
+}
+
+// New erstellt einen Simulator für die angegebene Fixture
+func New(fixture Fixture) *Simulator {
+	s := &Simulator{}
+	s.SetFixture(fixture)
+	return s
+}
+
+// SetFixture tauscht die Fixture zur Laufzeit aus und setzt die Zähler zurück
+func (s *Simulator) SetFixture(fixture Fixture) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixture = fixture
+	s.requests = make(map[string]int)
+	s.unknown = make(map[string]int)
+	s.unauthorized = 0
+}
+
+// Requests liefert die Anzahl der beantworteten Anfragen an einen Endpunkt der Fixture.
+// Anfragen ohne gültiges Token und an unbekannte Endpunkte zählen nicht.
+func (s *Simulator) Requests(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[endpoint]
+}
+
+// UnknownRequests liefert die Anzahl der Anfragen an einen Endpunkt, den die Fixture nicht enthält
+func (s *Simulator) UnknownRequests(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.unknown[endpoint]
+}
+
+// UnauthorizedRequests liefert die Anzahl der Anfragen ohne gültiges Token
+func (s *Simulator) UnauthorizedRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.unauthorized
+}
+
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, BasePath) {
+		http.NotFound(w, r)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, BasePath)
+
+	response, status := s.next(name, r.Header.Get("Authorization"))
+	switch status {
+	case http.StatusUnauthorized:
+		writeJSON(w, status, map[string]string{"error": "Unauthorized"})
+		return
+	case http.StatusNotFound:
+		writeJSON(w, status, map[string]string{"error": "Not Found"})
+		return
+	}
+
+	if response.Delay > 0 {
+		select {
+		case <-time.After(response.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	status = response.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	switch {
+	case response.Empty:
+		w.WriteHeader(status)
+	case response.Malformed:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, `{"state": "OK", "message": `)
+	case response.Raw != "":
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, response.Raw)
+	default:
+		writeJSON(w, status, convertYAML(response.Body))
+	}
+}
+
+// next prüft das Token, liefert die nächste Antwort eines Endpunkts und zählt die Anfrage.
+// Der Status ist 401 ohne gültiges Token, 404 für unbekannte Endpunkte und sonst 200.
+func (s *Simulator) next(name, authorization string) (Response, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fixture.Token != "" && authorization != s.fixture.Token {
+		s.unauthorized++
+		return Response{}, http.StatusUnauthorized
+	}
+	endpoint, ok := s.fixture.Endpoints[name]
+	if !ok {
+		s.unknown[name]++
+		return Response{}, http.StatusNotFound
+	}
+	count := s.requests[name]
+	s.requests[name]++
+	if len(endpoint.Sequence) > 0 {
+		return endpoint.Sequence[count%len(endpoint.Sequence)], http.StatusOK
+	}
+	return endpoint.Response, http.StatusOK
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// convertYAML wandelt die von yaml.v2 gelieferten map[interface{}]interface{} in JSON-kompatible Typen um
+func convertYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = convertYAML(item)
+		}
+		return m
+	case []interface{}:
+		// Neue Liste statt Änderung in place, die Fixture wird von parallelen Anfragen geteilt
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = convertYAML(item)
+		}
+		return list
+	}
+	return value
+}
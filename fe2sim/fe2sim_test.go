@@ -0,0 +1,128 @@
+package fe2sim
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func loadExample(t *testing.T) *Simulator {
+	t.Helper()
+	fixture, err := Load("fixtures/example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(fixture)
+}
+
+// get fragt einen Endpunkt des Simulators ab, token leer bedeutet ohne Authorization-Header
+func get(simulator *Simulator, endpoint, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, BasePath+endpoint, nil)
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	rec := httptest.NewRecorder()
+	simulator.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSimulatorAuthorization(t *testing.T) {
+	simulator := loadExample(t)
+	for _, token := range []string{"", "wrong"} {
+		if rec := get(simulator, "status", token); rec.Code != http.StatusUnauthorized {
+			t.Errorf("token %q: status %d, want 401", token, rec.Code)
+		}
+	}
+	if rec := get(simulator, "status", "secret"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"state":"OK"`) {
+		t.Errorf("got %d %s, want 200 with status", rec.Code, rec.Body.String())
+	}
+	if got := simulator.UnauthorizedRequests(); got != 2 {
+		t.Errorf("unauthorized requests = %d, want 2", got)
+	}
+	if got := simulator.Requests("status"); got != 1 {
+		t.Errorf("status requests = %d, want 1 without the rejected ones", got)
+	}
+}
+
+func TestSimulatorUnknownEndpoint(t *testing.T) {
+	simulator := loadExample(t)
+	if rec := get(simulator, "license", "secret"); rec.Code != http.StatusNotFound {
+		t.Errorf("status %d, want 404", rec.Code)
+	}
+	// Ohne Token wird zuerst die Authentifizierung abgelehnt, wie bei FE2
+	if rec := get(simulator, "license", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("status %d, want 401", rec.Code)
+	}
+	rec := httptest.NewRecorder()
+	simulator.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/rest/other", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("path outside %s: status %d, want 404", BasePath, rec.Code)
+	}
+	if got := simulator.UnknownRequests("license"); got != 1 {
+		t.Errorf("unknown requests = %d, want 1", got)
+	}
+	if got := simulator.Requests("license"); got != 0 {
+		t.Errorf("requests = %d, want 0 for an unknown endpoint", got)
+	}
+}
+
+func TestSimulatorSequence(t *testing.T) {
+	simulator := loadExample(t)
+	want := []string{`"state":"OK"`, `"state":"ERROR"`, `"state":"OK"`}
+	for i, state := range want {
+		if rec := get(simulator, "cloud", "secret"); !strings.Contains(rec.Body.String(), `{"service":"Push",`+state) {
+			t.Errorf("request %d: got %s, want Push %s", i+1, rec.Body.String(), state)
+		}
+	}
+	if got := simulator.Requests("cloud"); got != len(want) {
+		t.Errorf("requests = %d, want %d", got, len(want))
+	}
+
+	simulator.SetFixture(Fixture{})
+	if got := simulator.Requests("cloud"); got != 0 {
+		t.Errorf("requests after SetFixture = %d, want 0", got)
+	}
+}
+
+func TestRecordingsEmptyBody(t *testing.T) {
+	dir := t.TempDir()
+	for _, recording := range []Recording{
+		{Endpoint: "status", Status: http.StatusServiceUnavailable},
+		{Endpoint: "mqtt", Status: http.StatusOK, Body: `{"defaultBroker": "OK"}`},
+	} {
+		if err := SaveRecording(dir, recording); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fixture, err := LoadRecordings(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	simulator := New(fixture)
+	if rec := get(simulator, "status", ""); rec.Code != http.StatusServiceUnavailable || rec.Body.Len() != 0 {
+		t.Errorf("got %d %q, want 503 with an empty body", rec.Code, rec.Body.String())
+	}
+	if rec := get(simulator, "mqtt", ""); rec.Body.String() != `{"defaultBroker": "OK"}` {
+		t.Errorf("got %q, want the recorded body unchanged", rec.Body.String())
+	}
+}
+
+func TestSimulatorConcurrentRequests(t *testing.T) {
+	simulator := loadExample(t)
+	done := make(chan bool)
+	for i := 0; i < 4; i++ {
+		go func() {
+			for j := 0; j < 20; j++ {
+				get(simulator, "input", "secret")
+			}
+			done <- true
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+	if got := simulator.Requests("input"); got != 80 {
+		t.Errorf("requests = %d, want 80", got)
+	}
+}
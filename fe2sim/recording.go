@@ -0,0 +1,81 @@
+package fe2sim
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Recording ist eine aufgezeichnete Antwort der Monitoring-Schnittstelle (check_fe2 --record)
+type Recording struct {
+	Endpoint       string      `json:"endpoint"`
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	Time           time.Time   `json:"time"`
+	DurationMs     float64     `json:"durationMs"`
+	RequestHeaders http.Header `json:"requestHeaders"`
+	Status         int         `json:"status"`
+	Headers        http.Header `json:"headers"`
+	Body           string      `json:"body"`
+}
+
+// Redacted ersetzt Header-Werte, die das Token enthalten
+const Redacted = "<redacted>"
+
+// RedactHeaders liefert eine Kopie der Header ohne Authorization-Token
+func RedactHeaders(header http.Header) http.Header {
+	redacted := header.Clone()
+	for name := range redacted {
+		if strings.EqualFold(name, "Authorization") || strings.EqualFold(name, "Proxy-Authorization") {
+			redacted[name] = []string{Redacted}
+		}
+	}
+	return redacted
+}
+
+// RecordingFileName liefert den Dateinamen einer Aufzeichnung, z.B. "input_<id>.json"
+func RecordingFileName(endpoint string) string {
+	return strings.ReplaceAll(endpoint, "/", "_") + ".json"
+}
+
+// SaveRecording schreibt eine Aufzeichnung in das Verzeichnis dir
+func SaveRecording(dir string, recording Recording) error {
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(recording); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, RecordingFileName(recording.Endpoint)), data.Bytes(), 0600)
+}
+
+// LoadRecordings liest alle Aufzeichnungen eines Verzeichnisses als Fixture ohne Token-Prüfung
+func LoadRecordings(dir string) (Fixture, error) {
+	fixture := Fixture{Endpoints: make(map[string]Endpoint)}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return fixture, err
+	}
+	if len(files) == 0 {
+		return fixture, fmt.Errorf("no recordings found in %s", dir)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fixture, err
+		}
+		var recording Recording
+		if err := json.Unmarshal(data, &recording); err != nil {
+			return fixture, fmt.Errorf("error decoding recording %s: %w", file, err)
+		}
+		// Ein leerer Body bleibt leer und wird nicht als JSON null ausgeliefert
+		fixture.Endpoints[recording.Endpoint] = Endpoint{Response: Response{Status: recording.Status, Raw: recording.Body, Empty: recording.Body == ""}}
+	}
+	return fixture, nil
+}